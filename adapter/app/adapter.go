@@ -20,6 +20,8 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -45,8 +47,12 @@ type Adapter struct {
 	cancel func()
 
 	adapterServer          *grpc.Server
+	bindingServer          *binding.AdapterServer
+	drainTimeout           time.Duration
 	bindingManager         *binding.BindingManager
+	loadMeter              *egress.LoadMeter
 	maxBindings            int
+	zone                   string
 	logsAPIConnCount       int
 	logsAPIConnTTL         time.Duration
 	logsEgressAPITLSConfig *tls.Config
@@ -54,6 +60,17 @@ type Adapter struct {
 	syslogKeepalive        time.Duration
 	syslogDialTimeout      time.Duration
 	syslogIOTimeout        time.Duration
+	syslogFailback         time.Duration
+	syslogBufferSize       int
+	syslogLinger           time.Duration
+	syslogClientCert       *tls.Certificate
+	syslogTLSPolicy        *egress.TLSPolicy
+	syslogProxy            *egress.Proxy
+	syslogMessageIDs       bool
+	syslogRateLimit        egress.RateLimit
+	syslogAppRateLimit     egress.RateLimit
+	redactionPatterns      []egress.RedactionPattern
+	httpsMaxConnsPerHost   int
 	skipCertVerify         bool
 	health                 *health.Health
 	timeoutWaitGroup       *timeoutwaitgroup.TimeoutWaitGroup
@@ -85,6 +102,23 @@ func WithMaxBindings(i int) AdapterOption {
 	}
 }
 
+// WithAvailabilityZone sets the availability zone the adapter reports to
+// the scheduler.
+func WithAvailabilityZone(zone string) AdapterOption {
+	return func(c *Adapter) {
+		c.zone = zone
+	}
+}
+
+// WithDrainTimeout sets how long Stop waits for the scheduler to move the
+// bindings of the adapter to other adapters before it stops streaming. The
+// adapter does not wait if the timeout is zero.
+func WithDrainTimeout(d time.Duration) AdapterOption {
+	return func(c *Adapter) {
+		c.drainTimeout = d
+	}
+}
+
 // WithLogsEgressAPIConnCount sets the maximum number of connections to the
 // Loggregator API
 func WithLogsEgressAPIConnCount(m int) AdapterOption {
@@ -125,6 +159,67 @@ func WithSyslogIOTimeout(d time.Duration) AdapterOption {
 	}
 }
 
+// WithSyslogFailbackInterval sets how long a syslog writer uses a fallback
+// drain before it attempts to return to the primary drain.
+func WithSyslogFailbackInterval(d time.Duration) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogFailback = d
+	}
+}
+
+// WithSyslogWriteBuffer enables coalescing of writes to syslog and
+// syslog-tls drains. Messages are buffered until size bytes are buffered or
+// linger has passed. A size of zero disables buffering.
+func WithSyslogWriteBuffer(size int, linger time.Duration) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogBufferSize = size
+		a.syslogLinger = linger
+	}
+}
+
+// WithSyslogRateLimit caps the envelopes and payload bytes per second written
+// to each syslog drain. Drains may lower the limits with query parameters.
+// Burst is how many seconds worth of envelopes or bytes may be written at
+// once. A zero rate means no limit.
+func WithSyslogRateLimit(messages, bytes int, burst time.Duration) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogRateLimit = egress.RateLimit{
+			Messages: float64(messages),
+			Bytes:    float64(bytes),
+			Burst:    burst,
+		}
+	}
+}
+
+// WithSyslogAppRateLimit caps the envelopes and payload bytes per second
+// written to all syslog drains of an app together, so that apps with many
+// drains are not given a larger share of the adapter. Burst is the burst of
+// WithSyslogRateLimit. A zero rate means no limit.
+func WithSyslogAppRateLimit(messages, bytes int) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogAppRateLimit = egress.RateLimit{
+			Messages: float64(messages),
+			Bytes:    float64(bytes),
+		}
+	}
+}
+
+// WithSyslogHTTPSMaxConnsPerHost sets the maximum number of connections the
+// adapter opens to each HTTPS drain host. Zero means no limit.
+func WithSyslogHTTPSMaxConnsPerHost(n int) AdapterOption {
+	return func(a *Adapter) {
+		a.httpsMaxConnsPerHost = n
+	}
+}
+
+// WithSyslogRedaction masks the given patterns in log payloads before they
+// are written to syslog drains.
+func WithSyslogRedaction(patterns []egress.RedactionPattern) AdapterOption {
+	return func(a *Adapter) {
+		a.redactionPatterns = patterns
+	}
+}
+
 // WithSyslogSkipCertVerify sets the TCP InsecureSkipVerify property for
 // syslog
 func WithSyslogSkipCertVerify(b bool) AdapterOption {
@@ -133,6 +228,46 @@ func WithSyslogSkipCertVerify(b bool) AdapterOption {
 	}
 }
 
+// WithSyslogClientCert sets the client certificate presented to syslog-tls
+// and HTTPS drains that require mutual TLS. Bindings with a client
+// certificate of their own use it instead.
+func WithSyslogClientCert(cert *tls.Certificate) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogClientCert = cert
+	}
+}
+
+// WithSyslogTLSPolicy sets the TLS versions, cipher suites and curves used
+// for syslog-tls and HTTPS drains and the size of the session resumption
+// cache.
+func WithSyslogTLSPolicy(p *egress.TLSPolicy) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogTLSPolicy = p
+	}
+}
+
+// WithSyslogProxy routes drain connections through egress proxies. HTTPS
+// drains use the HTTPS proxy, syslog and syslog-tls drains are tunneled
+// through the syslog proxy with HTTP CONNECT or SOCKS5. Drains matching the
+// no proxy rules or with proxy=none in their URL connect directly. The
+// proxies only apply to drain connections.
+func WithSyslogProxy(p *egress.Proxy) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogProxy = p
+	}
+}
+
+// WithSyslogMessageIDs adds an ID to every message written to syslog drains
+// so receivers can drop the duplicates written by replicas of a binding.
+// It is disabled by default so that the messages of existing drains do not
+// change. Drains that request more than one replica with the replicas query
+// parameter get IDs regardless.
+func WithSyslogMessageIDs(enabled bool) AdapterOption {
+	return func(a *Adapter) {
+		a.syslogMessageIDs = enabled
+	}
+}
+
 // WithEnableMetricsToSyslog returns a AdapterOption to override the
 // default setting for writing metrics to syslog. By default this feature is
 // disabled.
@@ -170,7 +305,9 @@ func NewAdapter(
 		adapterServerTLSConfig: adapterServerTLSConfig,
 		syslogDialTimeout:      5 * time.Second,
 		syslogIOTimeout:        60 * time.Second,
-		skipCertVerify:         true,
+		syslogFailback:         30 * time.Second,
+		httpsMaxConnsPerHost:   50,
+		skipCertVerify:         false,
 		health:                 health.NewHealth(),
 		timeoutWaitGroup:       timeoutwaitgroup.New(time.Minute),
 		sourceIndex:            sourceIndex,
@@ -197,9 +334,25 @@ func NewAdapter(
 		time.Second,
 	)
 
+	transportPool := egress.NewTransportPool(
+		a.httpsMaxConnsPerHost,
+		// metric-documentation-v2: (adapter.https_connections) Number of
+		// open connections to HTTPS drains.
+		metricClient.NewGaugeMetric("https_connections", "connections",
+			pulseemitter.WithVersion(2, 0),
+		),
+		// metric-documentation-v2: (adapter.https_idle_connections) Number
+		// of open connections to HTTPS drains that are not in use.
+		metricClient.NewGaugeMetric("https_idle_connections", "connections",
+			pulseemitter.WithVersion(2, 0),
+		),
+	)
+
+	a.loadMeter = egress.NewLoadMeter(a.maxBindings, 10*time.Second, transportPool)
+
 	constructors := map[string]egress.WriterConstructor{
 		"https": egress.RetryWrapper(
-			egress.NewHTTPSWriter,
+			transportPool.NewHTTPSWriter,
 			egress.ExponentialDuration,
 			maxRetries,
 			logClient,
@@ -247,18 +400,46 @@ func NewAdapter(
 		"syslog-tls": egressMetric,
 	}
 
+	appRateLimit := a.syslogAppRateLimit
+	appRateLimit.Burst = a.syslogRateLimit.Burst
+
+	connectorOpts := []egress.ConnectorOption{
+		egress.WithConstructors(constructors),
+		egress.WithDroppedMetrics(droppedMetrics),
+		egress.WithEgressMetrics(egressMetrics),
+		egress.WithLogClient(logClient, a.sourceIndex),
+		egress.WithRateLimit(a.syslogRateLimit),
+		egress.WithAppRateLimit(
+			appRateLimit,
+			// metric-documentation-v2: (adapter.app_rate_limited) Number of
+			// envelopes dropped because the drains of an app exceeded the
+			// app rate limit.
+			buildMetric(metricClient, "app_rate_limited"),
+		),
+		egress.WithLoadMeter(a.loadMeter),
+	}
+	if len(a.redactionPatterns) > 0 {
+		connectorOpts = append(
+			connectorOpts,
+			egress.WithRedactor(buildRedactor(metricClient, a.redactionPatterns)),
+		)
+	}
 	syslogConnector := egress.NewSyslogConnector(
 		egress.NetworkTimeoutConfig{
-			Keepalive:    a.syslogKeepalive,
-			DialTimeout:  a.syslogDialTimeout,
-			WriteTimeout: a.syslogIOTimeout,
+			Keepalive:        a.syslogKeepalive,
+			DialTimeout:      a.syslogDialTimeout,
+			WriteTimeout:     a.syslogIOTimeout,
+			FailbackInterval: a.syslogFailback,
+			WriteBufferSize:  a.syslogBufferSize,
+			WriteLinger:      a.syslogLinger,
+			ClientCert:       a.syslogClientCert,
+			TLSPolicy:        a.syslogTLSPolicy,
+			Proxy:            a.syslogProxy,
+			MessageIDs:       a.syslogMessageIDs,
 		},
 		a.skipCertVerify,
 		a.timeoutWaitGroup,
-		egress.WithConstructors(constructors),
-		egress.WithDroppedMetrics(droppedMetrics),
-		egress.WithEgressMetrics(egressMetrics),
-		egress.WithLogClient(logClient, a.sourceIndex),
+		connectorOpts...,
 	)
 	subscriber := ingress.NewSubscriber(
 		a.ctx,
@@ -267,6 +448,7 @@ func NewAdapter(
 		metricClient,
 		ingress.WithLogClient(logClient, a.sourceIndex),
 		ingress.WithMetricsToSyslogEnabled(a.metricsToSyslogEnabled),
+		ingress.WithLoadMeter(a.loadMeter),
 	)
 
 	a.bindingManager = binding.NewBindingManager(
@@ -288,6 +470,17 @@ func buildMetric(m MetricClient, name string) pulseemitter.CounterMetric {
 	)
 }
 
+func buildRedactor(m MetricClient, patterns []egress.RedactionPattern) *egress.Redactor {
+	metrics := make(map[string]pulseemitter.CounterMetric)
+	for _, p := range patterns {
+		// metric-documentation-v2: (adapter.redacted_<pattern>) Number of
+		// matches of a redaction pattern masked in log payloads.
+		metrics[p.Name] = buildMetric(m, "redacted_"+p.Name)
+	}
+
+	return egress.NewRedactor(patterns, metrics)
+}
+
 // Start starts the adapter health endpoint and gRPC service.
 func (a *Adapter) Start() error {
 	lis, err := net.Listen("tcp", a.adapterServerAddr) // close this listener
@@ -300,18 +493,27 @@ func (a *Adapter) Start() error {
 		PermitWithoutStream: true,
 	}
 
-	adapterServer := binding.NewAdapterServer(a.bindingManager, a.health)
+	adapterServer := binding.NewAdapterServer(
+		a.bindingManager,
+		a.health,
+		binding.WithLoadReporter(a.loadMeter),
+		binding.WithZone(a.zone),
+	)
 	grpcServer := grpc.NewServer(
 		grpc.Creds(credentials.NewTLS(a.adapterServerTLSConfig)),
 		grpc.KeepaliveEnforcementPolicy(kp),
 	)
 	v1.RegisterAdapterServer(grpcServer, adapterServer)
 
+	// The scheduler checks the adapter with the gRPC health protocol.
+	healthpb.RegisterHealthServer(grpcServer, grpchealth.NewServer())
+
 	log.Printf("Adapter server is listening on %s", lis.Addr().String())
 	a.adapterServer = grpcServer
 
 	a.mu.Lock()
 	a.adapterServerAddr = lis.Addr().String()
+	a.bindingServer = adapterServer
 	a.mu.Unlock()
 
 	return grpcServer.Serve(lis)
@@ -330,6 +532,8 @@ func (a *Adapter) ServerAddr() string {
 }
 
 func (a *Adapter) Stop() {
+	a.drain()
+
 	log.Printf("Draining connections...")
 
 	a.adapterServer.Stop()
@@ -339,3 +543,32 @@ func (a *Adapter) Stop() {
 	log.Printf("Done draining connections.")
 	log.Println("Shutting down adapter server")
 }
+
+// drain announces to the scheduler that the adapter is shutting down and
+// waits until the scheduler has deleted all bindings or the drain timeout
+// has elapsed. The scheduler deletes a binding once its replacement on
+// another adapter is streaming.
+func (a *Adapter) drain() {
+	a.mu.Lock()
+	server := a.bindingServer
+	a.mu.Unlock()
+
+	if a.drainTimeout <= 0 || server == nil {
+		return
+	}
+
+	server.Drain()
+	log.Printf("Waiting for bindings to be moved to other adapters...")
+
+	deadline := time.Now().Add(a.drainTimeout)
+	for time.Now().Before(deadline) {
+		n := len(a.bindingManager.List())
+		if n == 0 {
+			log.Printf("All bindings were moved.")
+			return
+		}
+		time.Sleep(time.Second)
+	}
+
+	log.Printf("Drain timeout elapsed with %d bindings left.", len(a.bindingManager.List()))
+}
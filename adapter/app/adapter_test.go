@@ -190,6 +190,16 @@ var _ = Describe("Adapter", func() {
 					Consistently(syslogTCPServer.msgCount, "100ms").Should(BeNumerically("~", currentCount, 2))
 				})
 			})
+
+			It("does not add message IDs by default", func() {
+				_, err := client.CreateBinding(context.Background(), &v1.CreateBindingRequest{
+					Binding: binding,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(syslogTCPServer.msgCount).Should(BeNumerically(">", 0))
+				Expect(syslogTCPServer.messageIDCount()).To(BeZero())
+			})
 		})
 
 		Context("with skip ssl validation disabled", func() {
@@ -312,6 +322,7 @@ type SyslogTCPServer struct {
 	lis             net.Listener
 	mu              sync.Mutex
 	msgCount_       uint64
+	idCount         uint64
 	lastReceivedIdx int64
 }
 
@@ -375,6 +386,12 @@ func (m *SyslogTCPServer) handleConn(conn net.Conn) {
 			fmt.Println("Failed to parse", err)
 		}
 
+		for _, sd := range msg.StructuredData {
+			if sd.ID == "dedup@47450" {
+				atomic.AddUint64(&m.idCount, 1)
+			}
+		}
+
 		atomic.AddUint64(&m.msgCount_, 1)
 	}
 }
@@ -383,6 +400,10 @@ func (m *SyslogTCPServer) msgCount() uint64 {
 	return atomic.LoadUint64(&m.msgCount_)
 }
 
+func (m *SyslogTCPServer) messageIDCount() uint64 {
+	return atomic.LoadUint64(&m.idCount)
+}
+
 func (m *SyslogTCPServer) addr() net.Addr {
 	return m.lis.Addr()
 }
@@ -1,11 +1,14 @@
 package app
 
 import (
+	"encoding/json"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
 	"golang.org/x/net/idna"
 )
 
@@ -28,13 +31,56 @@ type Config struct {
 	SyslogKeepalive        time.Duration `env:"SYSLOG_KEEPALIVE"`
 	SyslogDialTimeout      time.Duration `env:"SYSLOG_DIAL_TIMEOUT"`
 	SyslogIOTimeout        time.Duration `env:"SYSLOG_IO_TIMEOUT"`
+	SyslogFailbackInterval time.Duration `env:"SYSLOG_FAILBACK_INTERVAL"`
+	SyslogWriteBufferSize  int           `env:"SYSLOG_WRITE_BUFFER_SIZE"`
+	SyslogWriteLinger      time.Duration `env:"SYSLOG_WRITE_LINGER"`
+	SyslogRateLimit        int           `env:"SYSLOG_RATE_LIMIT"`
+	SyslogRateLimitBytes   int           `env:"SYSLOG_RATE_LIMIT_BYTES"`
+	SyslogRateLimitBurst   time.Duration `env:"SYSLOG_RATE_LIMIT_BURST"`
+	SyslogAppRateLimit     int           `env:"SYSLOG_APP_RATE_LIMIT"`
+	SyslogAppRateBytes     int           `env:"SYSLOG_APP_RATE_LIMIT_BYTES"`
+	SyslogHTTPSMaxConns    int           `env:"SYSLOG_HTTPS_MAX_CONNS_PER_HOST"`
+	SyslogRedactionEnabled bool          `env:"SYSLOG_REDACTION_ENABLED"`
+	SyslogRedactionJSON    string        `env:"SYSLOG_REDACTION_PATTERNS"`
 	SyslogSkipCertVerify   bool          `env:"SYSLOG_SKIP_CERT_VERIFY"`
+	SyslogClientCertFile   string        `env:"SYSLOG_CLIENT_CERT_FILE_PATH"`
+	SyslogClientKeyFile    string        `env:"SYSLOG_CLIENT_KEY_FILE_PATH"`
+	SyslogTLSMinVersion    string        `env:"SYSLOG_TLS_MIN_VERSION"`
+	SyslogTLSMaxVersion    string        `env:"SYSLOG_TLS_MAX_VERSION"`
+	SyslogTLSCipherSuites  []string      `env:"SYSLOG_TLS_CIPHER_SUITES"`
+	SyslogTLSCurves        []string      `env:"SYSLOG_TLS_CURVES"`
+	SyslogTLSSessionCache  int           `env:"SYSLOG_TLS_SESSION_CACHE_SIZE"`
+	SyslogProxy            string        `env:"SYSLOG_PROXY"`
+	SyslogHTTPSProxy       string        `env:"SYSLOG_HTTPS_PROXY"`
+	SyslogNoProxy          string        `env:"SYSLOG_NO_PROXY"`
+	SyslogMessageIDs       bool          `env:"SYSLOG_MESSAGE_IDS"`
 	MetricsToSyslogEnabled bool          `env:"METRICS_TO_SYSLOG_ENABLED"`
 	MaxBindings            int           `env:"MAX_BINDINGS"`
+	AvailabilityZone       string        `env:"AVAILABILITY_ZONE"`
+	DrainTimeout           time.Duration `env:"DRAIN_TIMEOUT"`
 
 	MetricIngressAddr     string        `env:"METRIC_INGRESS_ADDR,     required"`
 	MetricIngressCN       string        `env:"METRIC_INGRESS_CN,       required"`
 	MetricEmitterInterval time.Duration `env:"METRIC_EMITTER_INTERVAL"`
+
+	// SyslogRedactionPatterns maps names to regular expressions that are
+	// masked in log payloads. It is loaded from the JSON object in
+	// SYSLOG_REDACTION_PATTERNS.
+	SyslogRedactionPatterns map[string]string
+
+	// SyslogRedaction holds the builtin redaction patterns if
+	// SYSLOG_REDACTION_ENABLED is set followed by the patterns of
+	// SYSLOG_REDACTION_PATTERNS in order of their names.
+	SyslogRedaction []egress.RedactionPattern
+
+	// SyslogTLSPolicy is built from the SYSLOG_TLS_* settings.
+	SyslogTLSPolicy *egress.TLSPolicy
+
+	// SyslogProxies is built from SYSLOG_PROXY, SYSLOG_HTTPS_PROXY and
+	// SYSLOG_NO_PROXY. The standard HTTPS_PROXY and NO_PROXY variables are
+	// not used because they also apply to the gRPC connections of the
+	// adapter.
+	SyslogProxies *egress.Proxy
 }
 
 // LoadConfig will load and validate the config from the current environment.
@@ -47,10 +93,17 @@ func LoadConfig() *Config {
 		PprofHostport:          "localhost:6060",
 		SyslogDialTimeout:      5 * time.Second,
 		SyslogIOTimeout:        time.Minute,
+		SyslogFailbackInterval: 30 * time.Second,
+		SyslogWriteLinger:      10 * time.Millisecond,
+		SyslogRateLimitBurst:   time.Second,
+		SyslogHTTPSMaxConns:    50,
 		SyslogSkipCertVerify:   false,
+		SyslogTLSMinVersion:    "1.2",
+		SyslogTLSSessionCache:  1024,
 		MetricEmitterInterval:  time.Minute,
 		MetricsToSyslogEnabled: false,
 		MaxBindings:            500,
+		DrainTimeout:           time.Minute,
 	}
 
 	err := envstruct.Load(&cfg)
@@ -64,5 +117,50 @@ func LoadConfig() *Config {
 	}
 	cfg.LogsAPIAddrWithAZ = strings.Replace(cfg.LogsAPIAddrWithAZ, "@", "-", -1)
 
+	if cfg.SyslogRedactionJSON != "" {
+		err = json.Unmarshal([]byte(cfg.SyslogRedactionJSON), &cfg.SyslogRedactionPatterns)
+		if err != nil {
+			log.Fatalf("failed to parse SYSLOG_REDACTION_PATTERNS: %s", err)
+		}
+	}
+
+	if (cfg.SyslogClientCertFile == "") != (cfg.SyslogClientKeyFile == "") {
+		log.Fatalf("SYSLOG_CLIENT_CERT_FILE_PATH and SYSLOG_CLIENT_KEY_FILE_PATH must be set together")
+	}
+
+	if cfg.SyslogRedactionEnabled {
+		cfg.SyslogRedaction = egress.BuiltinRedactionPatterns()
+	}
+
+	names := make([]string, 0, len(cfg.SyslogRedactionPatterns))
+	for name := range cfg.SyslogRedactionPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p, err := egress.NewRedactionPattern(name, cfg.SyslogRedactionPatterns[name])
+		if err != nil {
+			log.Fatalf("invalid redaction pattern %s: %s", name, err)
+		}
+		cfg.SyslogRedaction = append(cfg.SyslogRedaction, p)
+	}
+
+	cfg.SyslogTLSPolicy, err = egress.NewTLSPolicy(
+		cfg.SyslogTLSMinVersion,
+		cfg.SyslogTLSMaxVersion,
+		cfg.SyslogTLSCipherSuites,
+		cfg.SyslogTLSCurves,
+		cfg.SyslogTLSSessionCache,
+	)
+	if err != nil {
+		log.Fatalf("invalid syslog TLS policy: %s", err)
+	}
+
+	cfg.SyslogProxies, err = egress.NewProxy(cfg.SyslogHTTPSProxy, cfg.SyslogProxy, cfg.SyslogNoProxy)
+	if err != nil {
+		log.Fatalf("invalid syslog proxy: %s", err)
+	}
+
 	return &cfg
 }
@@ -1,6 +1,10 @@
 package binding
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -19,23 +23,69 @@ type HealthEmitter interface {
 	SetCounter(map[string]int)
 }
 
+// LoadReporter reports the load of the adapter for its bindings.
+type LoadReporter interface {
+	Load(bindings []*v1.Binding) *v1.GetLoadResponse
+}
+
 // AdapterServer implements the v1.AdapterServer interface.
 type AdapterServer struct {
 	store  BindingStore
 	health HealthEmitter
+	load   LoadReporter
+	zone   string
+
+	draining int32
+
+	leaseMu      sync.Mutex
+	leaseHolder  string
+	leaseExpires time.Time
+}
+
+// AdapterServerOption is a function that can be used to configure optional
+// settings on an AdapterServer.
+type AdapterServerOption func(*AdapterServer)
+
+// WithLoadReporter sets the reporter that measures the load returned by
+// GetLoad. Without one only the bindings are reported.
+func WithLoadReporter(r LoadReporter) AdapterServerOption {
+	return func(c *AdapterServer) {
+		c.load = r
+	}
+}
+
+// WithZone sets the availability zone that GetLoad reports so that the
+// scheduler can spread the replicas of a binding across zones.
+func WithZone(zone string) AdapterServerOption {
+	return func(c *AdapterServer) {
+		c.zone = zone
+	}
 }
 
 // New returns a new AdapterServer.
-func NewAdapterServer(store BindingStore, health HealthEmitter) *AdapterServer {
-	return &AdapterServer{
+func NewAdapterServer(store BindingStore, health HealthEmitter, opts ...AdapterServerOption) *AdapterServer {
+	c := &AdapterServer{
 		store:  store,
 		health: health,
 	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
 }
 
-// ListBindings returns a list of bindings from the binding manager
+// ListBindings returns a list of bindings from the binding manager. The
+// bindings are listed by their identity so that their credentials are not
+// returned.
 func (c *AdapterServer) ListBindings(ctx context.Context, req *v1.ListBindingsRequest) (*v1.ListBindingsResponse, error) {
-	return &v1.ListBindingsResponse{Bindings: c.store.List()}, nil
+	var bindings []*v1.Binding
+	for _, b := range c.store.List() {
+		bindings = append(bindings, identityOf(b))
+	}
+
+	return &v1.ListBindingsResponse{Bindings: bindings}, nil
 }
 
 // CreateBinding adds a new binding to the binding manager.
@@ -60,3 +110,84 @@ func (c *AdapterServer) DeleteBinding(ctx context.Context, req *v1.DeleteBinding
 
 	return &v1.DeleteBindingResponse{}, nil
 }
+
+// GetLoad reports the load of the adapter and of each of its bindings. Like
+// ListBindings it reports the bindings by their identity.
+func (c *AdapterServer) GetLoad(ctx context.Context, req *v1.GetLoadRequest) (*v1.GetLoadResponse, error) {
+	bindings := c.store.List()
+
+	var resp *v1.GetLoadResponse
+	if c.load != nil {
+		resp = c.load.Load(bindings)
+	} else {
+		resp = &v1.GetLoadResponse{}
+		for _, b := range bindings {
+			resp.Bindings = append(resp.Bindings, &v1.BindingLoad{Binding: b})
+		}
+	}
+	for _, bl := range resp.Bindings {
+		bl.Binding = identityOf(bl.Binding)
+	}
+	resp.Zone = c.zone
+	resp.Draining = atomic.LoadInt32(&c.draining) == 1
+
+	return resp, nil
+}
+
+// identityOf returns the identity of the binding, which holds a digest of
+// its credentials instead of the credentials.
+func identityOf(b *v1.Binding) *v1.Binding {
+	if b == nil {
+		return nil
+	}
+
+	id := v1.Identity(*b)
+	return &id
+}
+
+// Drain announces to the scheduler that the adapter is shutting down so
+// that its bindings are moved to other adapters.
+func (c *AdapterServer) Drain() {
+	atomic.StoreInt32(&c.draining, 1)
+}
+
+// AcquireLease grants the leader lease of the schedulers to the holder if
+// no other holder owns a lease that has not expired. The expiry is measured
+// with the clock of the adapter, so the schedulers do not need synchronized
+// clocks.
+//
+// The lease is only kept in memory. An adapter that restarts forgets the
+// lease, so while the adapters are restarted one after another two
+// schedulers may both hold the lease for up to a third of its ttl, until
+// the leader renews it.
+func (c *AdapterServer) AcquireLease(ctx context.Context, req *v1.AcquireLeaseRequest) (*v1.AcquireLeaseResponse, error) {
+	if req.Holder == "" || req.TtlMillis <= 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument, "holder and ttl are required")
+	}
+
+	c.leaseMu.Lock()
+	defer c.leaseMu.Unlock()
+
+	now := time.Now()
+	if c.leaseHolder != "" && c.leaseHolder != req.Holder && now.Before(c.leaseExpires) {
+		return &v1.AcquireLeaseResponse{}, nil
+	}
+
+	c.leaseHolder = req.Holder
+	c.leaseExpires = now.Add(time.Duration(req.TtlMillis) * time.Millisecond)
+
+	return &v1.AcquireLeaseResponse{Acquired: true}, nil
+}
+
+// ReleaseLease gives up the leader lease if the holder owns it.
+func (c *AdapterServer) ReleaseLease(ctx context.Context, req *v1.ReleaseLeaseRequest) (*v1.ReleaseLeaseResponse, error) {
+	c.leaseMu.Lock()
+	defer c.leaseMu.Unlock()
+
+	if c.leaseHolder == req.Holder {
+		c.leaseHolder = ""
+		c.leaseExpires = time.Time{}
+	}
+
+	return &v1.ReleaseLeaseResponse{}, nil
+}
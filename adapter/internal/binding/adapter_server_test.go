@@ -35,6 +35,30 @@ var _ = Describe("AdapterServer", func() {
 		Expect(resp.Bindings).To(HaveLen(2))
 	})
 
+	It("lists bindings without their credentials", func() {
+		b := &v1.Binding{
+			AppId:      "some-app-id",
+			Hostname:   "some-host",
+			Drain:      "syslog-tls://some.url",
+			ClientCert: "some-cert",
+			ClientKey:  "some-key",
+		}
+		store := &SpyStore{list: []*v1.Binding{b}}
+		adapterServer := binding.NewAdapterServer(store, healthEmitter)
+
+		resp, err := adapterServer.ListBindings(
+			context.Background(),
+			&v1.ListBindingsRequest{},
+		)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Bindings).To(HaveLen(1))
+		Expect(*resp.Bindings[0]).To(Equal(v1.Identity(*b)))
+		Expect(resp.Bindings[0].ClientKey).To(BeEmpty())
+		Expect(resp.Bindings[0].CredentialsDigest).ToNot(BeEmpty())
+		Expect(b.ClientKey).To(Equal("some-key"))
+	})
+
 	It("adds new binding", func() {
 		store := &SpyStore{list: []*v1.Binding{}}
 		adapterServer := binding.NewAdapterServer(store, healthEmitter)
@@ -158,8 +182,173 @@ var _ = Describe("AdapterServer", func() {
 			"drainCount": 0,
 		}))
 	})
+
+	It("reports the load of its bindings", func() {
+		store := &SpyStore{list: []*v1.Binding{{AppId: "some-app-id"}}}
+		reporter := &SpyLoadReporter{
+			load: &v1.GetLoadResponse{IngressRate: 10},
+		}
+		adapterServer := binding.NewAdapterServer(
+			store,
+			healthEmitter,
+			binding.WithLoadReporter(reporter),
+		)
+
+		resp, err := adapterServer.GetLoad(
+			context.Background(),
+			&v1.GetLoadRequest{},
+		)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp).To(Equal(reporter.load))
+		Expect(reporter.bindings).To(Equal(store.list))
+	})
+
+	It("reports its bindings without a load reporter", func() {
+		store := &SpyStore{list: []*v1.Binding{{AppId: "some-app-id"}}}
+		adapterServer := binding.NewAdapterServer(store, healthEmitter)
+
+		resp, err := adapterServer.GetLoad(
+			context.Background(),
+			&v1.GetLoadRequest{},
+		)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Bindings).To(HaveLen(1))
+		Expect(resp.Bindings[0].Binding).To(Equal(store.list[0]))
+	})
+
+	It("reports the load of its bindings without their credentials", func() {
+		b := &v1.Binding{
+			AppId:     "some-app-id",
+			Drain:     "syslog-tls://some.url",
+			ClientKey: "some-key",
+		}
+		store := &SpyStore{list: []*v1.Binding{b}}
+		reporter := &SpyLoadReporter{
+			load: &v1.GetLoadResponse{
+				Bindings: []*v1.BindingLoad{{Binding: b, IngressRate: 10}},
+			},
+		}
+		adapterServer := binding.NewAdapterServer(
+			store,
+			healthEmitter,
+			binding.WithLoadReporter(reporter),
+		)
+
+		resp, err := adapterServer.GetLoad(
+			context.Background(),
+			&v1.GetLoadRequest{},
+		)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Bindings).To(HaveLen(1))
+		Expect(*resp.Bindings[0].Binding).To(Equal(v1.Identity(*b)))
+		Expect(resp.Bindings[0].Binding.ClientKey).To(BeEmpty())
+		Expect(resp.Bindings[0].IngressRate).To(Equal(10.0))
+	})
+
+	It("reports its availability zone", func() {
+		store := &SpyStore{}
+		adapterServer := binding.NewAdapterServer(
+			store,
+			healthEmitter,
+			binding.WithLoadReporter(&SpyLoadReporter{
+				load: &v1.GetLoadResponse{},
+			}),
+			binding.WithZone("z1"),
+		)
+
+		resp, err := adapterServer.GetLoad(
+			context.Background(),
+			&v1.GetLoadRequest{},
+		)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Zone).To(Equal("z1"))
+	})
+
+	It("reports that it is draining", func() {
+		adapterServer := binding.NewAdapterServer(&SpyStore{}, healthEmitter)
+
+		resp, err := adapterServer.GetLoad(context.Background(), &v1.GetLoadRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Draining).To(BeFalse())
+
+		adapterServer.Drain()
+
+		resp, err = adapterServer.GetLoad(context.Background(), &v1.GetLoadRequest{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Draining).To(BeTrue())
+	})
+
+	Describe("leases", func() {
+		var adapterServer *binding.AdapterServer
+
+		BeforeEach(func() {
+			adapterServer = binding.NewAdapterServer(&SpyStore{}, healthEmitter)
+		})
+
+		acquire := func(holder string, ttlMillis int64) bool {
+			resp, err := adapterServer.AcquireLease(
+				context.Background(),
+				&v1.AcquireLeaseRequest{Holder: holder, TtlMillis: ttlMillis},
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			return resp.Acquired
+		}
+
+		It("grants the lease to one holder at a time", func() {
+			Expect(acquire("a", 60000)).To(BeTrue())
+			Expect(acquire("a", 60000)).To(BeTrue())
+			Expect(acquire("b", 60000)).To(BeFalse())
+		})
+
+		It("grants an expired lease to another holder", func() {
+			Expect(acquire("a", 1)).To(BeTrue())
+
+			Eventually(func() bool { return acquire("b", 60000) }).Should(BeTrue())
+		})
+
+		It("grants a released lease to another holder", func() {
+			Expect(acquire("a", 60000)).To(BeTrue())
+
+			_, err := adapterServer.ReleaseLease(
+				context.Background(),
+				&v1.ReleaseLeaseRequest{Holder: "b"},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(acquire("b", 60000)).To(BeFalse())
+
+			_, err = adapterServer.ReleaseLease(
+				context.Background(),
+				&v1.ReleaseLeaseRequest{Holder: "a"},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(acquire("b", 60000)).To(BeTrue())
+		})
+
+		It("returns InvalidArgument without a holder or ttl", func() {
+			_, err := adapterServer.AcquireLease(
+				context.Background(),
+				&v1.AcquireLeaseRequest{Holder: "a"},
+			)
+			Expect(grpc.Code(err)).To(Equal(codes.InvalidArgument))
+		})
+	})
 })
 
+type SpyLoadReporter struct {
+	bindings []*v1.Binding
+	load     *v1.GetLoadResponse
+}
+
+func (s *SpyLoadReporter) Load(bindings []*v1.Binding) *v1.GetLoadResponse {
+	s.bindings = bindings
+	return s.load
+}
+
 type SpyHealthEmitter struct {
 	setCounter map[string]int
 }
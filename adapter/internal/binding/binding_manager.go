@@ -19,7 +19,9 @@ type MetricClient interface {
 	NewCounterMetric(name string, opts ...pulseemitter.MetricOption) pulseemitter.CounterMetric
 }
 
-// BindingManager stores binding subscriptions.
+// BindingManager stores binding subscriptions. The subscriptions are keyed
+// by the identity of their binding, so a binding can be deleted by its
+// identity.
 type BindingManager struct {
 	mu            sync.RWMutex
 	subscriptions map[v1.Binding]subscription
@@ -81,7 +83,7 @@ func (c *BindingManager) Add(binding *v1.Binding) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	key := *binding
+	key := v1.Identity(*binding)
 	if _, ok := c.subscriptions[key]; !ok {
 		if len(c.subscriptions) >= c.maxBindings {
 			c.rejectedBindingsMetric.Increment(1)
@@ -112,7 +114,7 @@ func (c *BindingManager) Delete(binding *v1.Binding) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	key := *binding
+	key := v1.Identity(*binding)
 	s, ok := c.subscriptions[key]
 	if ok {
 		s.unsubscribe()
@@ -144,6 +144,22 @@ var _ = Describe("BindingManager", func() {
 			Expect(manager.List()).To(HaveLen(0))
 		})
 
+		It("removes a binding by its identity", func() {
+			binding := &v1.Binding{
+				AppId:     "some-id",
+				Hostname:  "some-hostname",
+				Drain:     "syslog-tls://some.url",
+				ClientKey: "some-key",
+			}
+			manager.Add(binding)
+
+			identity := v1.Identity(*binding)
+			manager.Delete(&identity)
+
+			Expect(manager.List()).To(HaveLen(0))
+			Expect(subscriber.stopCount).To(Equal(1))
+		})
+
 		It("unsubscribes a binding", func() {
 			binding := &v1.Binding{
 				AppId:    "some-id",
@@ -0,0 +1,218 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Balancing modes for syslog and syslog-tls drains. They are selected with
+// the balance query parameter of the drain URL.
+const (
+	balanceNone       = ""
+	balanceRoundRobin = "round-robin"
+	balanceLeastConn  = "least-conn"
+)
+
+// Resolver looks up the addresses that serve a drain host. It is satisfied
+// by *net.Resolver.
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// target is an address a writer can connect to. The server name is the host
+// of the drain URL and is used to verify TLS certificates. It is never taken
+// from DNS records, which are not authenticated.
+type target struct {
+	addr       string
+	serverName string
+}
+
+// openConns counts the open drain connections per address for all writers
+// in the adapter. It is used for least-connection balancing.
+var openConns = &connCounter{counts: make(map[string]int)}
+
+type connCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *connCounter) add(addr string, delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[addr] += delta
+	if c.counts[addr] <= 0 {
+		delete(c.counts, addr)
+	}
+}
+
+// total returns the number of open connections to all addresses.
+func (c *connCounter) total() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for _, count := range c.counts {
+		n += count
+	}
+
+	return n
+}
+
+// sort orders the targets by their number of open connections, fewest
+// first. Targets with the same number of connections keep their order.
+func (c *connCounter) sort(targets []target) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return c.counts[targets[i].addr] < c.counts[targets[j].addr]
+	})
+}
+
+// connPool holds the open connections of a writer, at most one per target,
+// and hands them out in round-robin order.
+type connPool struct {
+	addrs []string
+	conns []net.Conn
+	next  int
+}
+
+func (p *connPool) empty() bool {
+	return len(p.conns) == 0
+}
+
+func (p *connPool) add(addr string, conn net.Conn) {
+	openConns.add(addr, 1)
+	p.addrs = append(p.addrs, addr)
+	p.conns = append(p.conns, conn)
+}
+
+func (p *connPool) get() net.Conn {
+	conn := p.conns[p.next%len(p.conns)]
+	p.next++
+
+	return conn
+}
+
+func (p *connPool) close() error {
+	var err error
+	for i, conn := range p.conns {
+		openConns.add(p.addrs[i], -1)
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	p.addrs = nil
+	p.conns = nil
+	p.next = 0
+
+	return err
+}
+
+// balanceMode returns the balancing mode requested by the drain URL.
+func balanceMode(b *URLBinding) string {
+	mode := b.Options.Get("balance")
+	switch mode {
+	case balanceNone, balanceRoundRobin, balanceLeastConn:
+		return mode
+	default:
+		log.Printf("unknown balance mode %q for syslog drain %s", mode, b.drainHost())
+		return balanceNone
+	}
+}
+
+// resolveTargets returns the addresses to connect to for a drain URL. Drain
+// hosts without a port are looked up through _syslog._tcp SRV records, in
+// order of priority. IP addresses are never looked up. When expand is true
+// every A and AAAA record of a host becomes a separate target. Lookups
+// happen each time a writer connects so DNS changes are picked up on
+// reconnect.
+func resolveTargets(
+	r Resolver,
+	u *url.URL,
+	expand bool,
+	timeout time.Duration,
+) ([]target, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	targets := []target{{addr: u.Host, serverName: u.Hostname()}}
+	if u.Port() == "" && net.ParseIP(u.Hostname()) == nil {
+		var err error
+		targets, err = lookupSRV(ctx, r, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !expand {
+		return targets, nil
+	}
+
+	var expanded []target
+	for _, t := range targets {
+		host, port, err := net.SplitHostPort(t.addr)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			log.Printf("failed to resolve syslog drain host %s: %s", host, err)
+			continue
+		}
+
+		for _, a := range addrs {
+			expanded = append(expanded, target{
+				addr:       net.JoinHostPort(a, port),
+				serverName: t.serverName,
+			})
+		}
+	}
+
+	if len(expanded) == 0 {
+		return nil, errors.New("no addresses found for syslog drain")
+	}
+
+	return expanded, nil
+}
+
+// lookupSRV returns the targets of the SRV records of the host. The targets
+// keep the host as their server name, so that the certificates of the
+// targets are verified for the drain host as RFC 6125 requires.
+func lookupSRV(ctx context.Context, r Resolver, host string) ([]target, error) {
+	_, records, err := r.LookupSRV(ctx, "syslog", "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("no SRV records found for syslog drain")
+	}
+
+	targets := make([]target, 0, len(records))
+	for _, rec := range records {
+		name := strings.TrimSuffix(rec.Target, ".")
+		targets = append(targets, target{
+			addr:       net.JoinHostPort(name, strconv.Itoa(int(rec.Port))),
+			serverName: host,
+		})
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,145 @@
+package egress
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+)
+
+// bufferedConn coalesces the messages written to a syslog drain connection
+// into larger writes. Buffered data is written when the next message does
+// not fit into the buffer or when the linger interval has passed since data
+// was first buffered. The write timeout applies to each of these flushes.
+// Each write is expected to be a single message. Messages are counted by
+// the egress metric once they have been flushed.
+type bufferedConn struct {
+	net.Conn
+
+	mu           sync.Mutex
+	buf          *bufio.Writer
+	linger       time.Duration
+	timer        *time.Timer
+	err          error
+	pending      uint64
+	egressMetric pulseemitter.CounterMetric
+}
+
+func newBufferedConn(
+	conn net.Conn,
+	size int,
+	linger time.Duration,
+	writeTimeout time.Duration,
+	egressMetric pulseemitter.CounterMetric,
+) *bufferedConn {
+	return &bufferedConn{
+		Conn: conn,
+		buf: bufio.NewWriterSize(&deadlineWriter{
+			conn:    conn,
+			timeout: writeTimeout,
+		}, size),
+		linger:       linger,
+		egressMetric: egressMetric,
+	}
+}
+
+// Write buffers the data. It returns the error of a failed flush, including
+// flushes that happened after the linger interval.
+func (c *bufferedConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	// Flush the buffered messages first so a message is either buffered as
+	// a whole or, if it is larger than the buffer, written directly.
+	if len(b) > c.buf.Available() && c.buf.Buffered() > 0 {
+		if err := c.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := c.buf.Write(b)
+	if err != nil {
+		c.err = err
+		return n, err
+	}
+
+	if c.buf.Buffered() == 0 {
+		c.egressMetric.Increment(1)
+		return n, nil
+	}
+
+	c.pending++
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.linger, c.lingerFlush)
+	}
+
+	return n, nil
+}
+
+// Close writes any buffered data and closes the connection.
+func (c *bufferedConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	if c.err == nil {
+		_ = c.flush()
+	}
+
+	return c.Conn.Close()
+}
+
+func (c *bufferedConn) lingerFlush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timer = nil
+	if c.err != nil {
+		return
+	}
+
+	_ = c.flush()
+}
+
+// flush writes the buffered messages and counts them. The messages are lost
+// if the flush fails. It must be called with the lock held.
+func (c *bufferedConn) flush() error {
+	pending := c.pending
+	c.pending = 0
+
+	if err := c.buf.Flush(); err != nil {
+		c.err = err
+		log.Printf("failed to write %d buffered messages to syslog drain: %s", pending, err)
+
+		return err
+	}
+
+	if pending > 0 {
+		c.egressMetric.Increment(pending)
+	}
+
+	return nil
+}
+
+// deadlineWriter sets the write deadline of a connection before each write.
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+
+	return w.conn.Write(b)
+}
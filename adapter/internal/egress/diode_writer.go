@@ -1,6 +1,8 @@
 package egress
 
 import (
+	"sync/atomic"
+
 	"golang.org/x/net/context"
 
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
@@ -8,12 +10,18 @@ import (
 	gendiodes "code.cloudfoundry.org/go-diodes"
 )
 
+// diodeBufferSize is the number of envelopes a DiodeWriter buffers before
+// it drops the oldest.
+const diodeBufferSize = 10000
+
 type WaitGroup interface {
 	Add(delta int)
 	Done()
 }
 
 type DiodeWriter struct {
+	pending int64
+
 	wc    WriteCloser
 	diode *diodes.OneToOne
 	wg    WaitGroup
@@ -28,11 +36,18 @@ func NewDiodeWriter(
 	wg WaitGroup,
 ) *DiodeWriter {
 	dw := &DiodeWriter{
-		wc:    wc,
-		diode: diodes.NewOneToOne(10000, alerter, gendiodes.WithPollingContext(ctx)),
-		wg:    wg,
-		ctx:   ctx,
+		wc:  wc,
+		wg:  wg,
+		ctx: ctx,
 	}
+	dw.diode = diodes.NewOneToOne(
+		diodeBufferSize,
+		gendiodes.AlertFunc(func(missed int) {
+			atomic.AddInt64(&dw.pending, -int64(missed))
+			alerter.Alert(missed)
+		}),
+		gendiodes.WithPollingContext(ctx),
+	)
 	wg.Add(1)
 	go dw.start()
 
@@ -41,11 +56,27 @@ func NewDiodeWriter(
 
 // Write writes an envelope into the diode. This can not fail.
 func (d *DiodeWriter) Write(env *loggregator_v2.Envelope) error {
+	atomic.AddInt64(&d.pending, 1)
 	d.diode.Set(env)
 
 	return nil
 }
 
+// Fill returns the share of the buffer that holds envelopes that have not
+// been written yet, between 0 and 1.
+func (d *DiodeWriter) Fill() float64 {
+	pending := atomic.LoadInt64(&d.pending)
+	if pending <= 0 {
+		return 0
+	}
+
+	if pending >= diodeBufferSize {
+		return 1
+	}
+
+	return float64(pending) / diodeBufferSize
+}
+
 func (d *DiodeWriter) start() {
 	defer d.wc.Close()
 	defer d.wg.Done()
@@ -55,6 +86,7 @@ func (d *DiodeWriter) start() {
 		if e == nil {
 			return
 		}
+		atomic.AddInt64(&d.pending, -1)
 
 		err := d.wc.Write(e)
 		if err != nil && contextDone(d.ctx) {
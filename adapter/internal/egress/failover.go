@@ -0,0 +1,64 @@
+package egress
+
+import (
+	"log"
+	"net/url"
+	"time"
+)
+
+// endpoints tracks which of a binding's drain URLs a writer is using. The
+// first URL is the primary drain and the rest are fallbacks in order of
+// preference. Writers stick with the URL in use until it fails and
+// periodically attempt to fail back to the more preferred URLs.
+type endpoints struct {
+	urls             []*url.URL
+	active           int
+	switchedAt       time.Time
+	failbackInterval time.Duration
+}
+
+func newEndpoints(b *URLBinding, failbackInterval time.Duration) *endpoints {
+	return &endpoints{
+		urls:             b.Endpoints(),
+		failbackInterval: failbackInterval,
+	}
+}
+
+// failbackDue reports whether a fallback URL is in use and it is time to try
+// the more preferred URLs again.
+func (e *endpoints) failbackDue() bool {
+	return e.active > 0 && time.Since(e.switchedAt) >= e.failbackInterval
+}
+
+// order returns the indexes of the URLs in the order they should be tried.
+func (e *endpoints) order() []int {
+	start := e.active
+	if e.failbackDue() {
+		start = 0
+	}
+
+	order := make([]int, 0, len(e.urls))
+	for i := range e.urls {
+		order = append(order, (start+i)%len(e.urls))
+	}
+
+	return order
+}
+
+// use marks the URL at the given index as in use. The failback timer is
+// restarted when the URL changes or when a failback attempt was due.
+func (e *endpoints) use(i int) {
+	if i == e.active && !e.failbackDue() {
+		return
+	}
+
+	if i != e.active {
+		log.Printf(
+			"switching syslog drain from %s to %s",
+			e.urls[e.active].Host, e.urls[i].Host,
+		)
+	}
+
+	e.active = i
+	e.switchedAt = time.Now()
+}
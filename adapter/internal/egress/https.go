@@ -6,71 +6,142 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
+	"sync"
 
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
-	"code.cloudfoundry.org/scalable-syslog/internal/api"
+	"code.cloudfoundry.org/rfc5424"
 )
 
 type HTTPSWriter struct {
 	hostname     string
 	appID        string
-	url          *url.URL
+	endpoints    *endpoints
 	client       *http.Client
+	headers      http.Header
+	signer       *signer
+	messages     messageWriter
 	egressMetric pulseemitter.CounterMetric
+	release      func()
+	closeOnce    sync.Once
 }
 
+// NewHTTPSWriter creates a new HTTPS syslog writer. Writers created with
+// NewHTTPSWriter share the connections of a default TransportPool.
 func NewHTTPSWriter(
 	binding *URLBinding,
 	netConf NetworkTimeoutConfig,
 	skipCertVerify bool,
 	egressMetric pulseemitter.CounterMetric,
 ) WriteCloser {
+	return defaultTransportPool.NewHTTPSWriter(
+		binding,
+		netConf,
+		skipCertVerify,
+		egressMetric,
+	)
+}
 
-	client := httpClient(netConf, skipCertVerify)
-
-	return &HTTPSWriter{
-		url:          binding.URL,
+func newHTTPSWriter(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	client *http.Client,
+	egressMetric pulseemitter.CounterMetric,
+) *HTTPSWriter {
+	w := &HTTPSWriter{
+		endpoints:    newEndpoints(binding, netConf.FailbackInterval),
 		appID:        binding.AppID,
 		hostname:     binding.Hostname,
 		client:       client,
+		headers:      binding.Headers,
+		signer:       newSigner(binding.SigningSecret),
 		egressMetric: egressMetric,
 	}
+	w.messages = newMessageWriter(binding, netConf, w.postMessage)
+
+	return w
 }
 
 func (w *HTTPSWriter) Write(env *loggregator_v2.Envelope) error {
-	msgs := generateRFC5424Messages(env, w.hostname, w.appID)
-	for _, msg := range msgs {
-		b, err := msg.MarshalBinary()
-		if err != nil {
-			return err
-		}
+	return w.messages.writeMessages(env, generateRFC5424Messages(env, w.hostname, w.appID))
+}
 
-		resp, err := w.client.Post(w.url.String(), "text/plain", bytes.NewBuffer(b))
+func (w *HTTPSWriter) postMessage(msg rfc5424.Message) error {
+	b, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.post(b)
+	if err != nil {
+		return err
+	}
+
+	// Drain and close the body before the next message so the connection
+	// can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Syslog Writer: Post responded with %d status code", resp.StatusCode)
+	}
+
+	w.egressMetric.Increment(1)
+
+	return nil
+}
+
+// post sends the message to the drain in use. If the drain can not be
+// reached the remaining drains are tried in order of preference.
+func (w *HTTPSWriter) post(b []byte) (*http.Response, error) {
+	var err error
+	for _, i := range w.endpoints.order() {
+		u := w.endpoints.urls[i]
+
+		var resp *http.Response
+		resp, err = w.do(u, b)
 		if err != nil {
-			return w.sanitizeError(w.url, err)
+			err = w.sanitizeError(u, err)
+			continue
 		}
-		defer func() {
-			io.Copy(ioutil.Discard, resp.Body)
-			resp.Body.Close()
-		}()
 
-		if resp.StatusCode < 200 || resp.StatusCode > 299 {
-			return fmt.Errorf("Syslog Writer: Post responded with %d status code", resp.StatusCode)
-		}
+		w.endpoints.use(i)
 
-		w.egressMetric.Increment(1)
+		return resp, nil
 	}
 
-	return nil
+	return nil, err
+}
+
+func (w *HTTPSWriter) do(u *url.URL, b []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range w.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	w.signer.sign(req, b)
+
+	return w.client.Do(req)
 }
 
-func (*HTTPSWriter) sanitizeError(u *url.URL, err error) error {
+// sanitizeError removes the credentials of the drain URL and the values of
+// the drain headers from the error.
+func (w *HTTPSWriter) sanitizeError(u *url.URL, err error) error {
+	for _, values := range w.headers {
+		for _, v := range values {
+			if v != "" {
+				err = errors.New(strings.Replace(err.Error(), v, "<REDACTED>", -1))
+			}
+		}
+	}
+
 	if u == nil || u.User == nil {
 		return err
 	}
@@ -85,28 +156,14 @@ func (*HTTPSWriter) sanitizeError(u *url.URL, err error) error {
 	return err
 }
 
-func (*HTTPSWriter) Close() error {
-	return nil
-}
-
-func httpClient(netConf NetworkTimeoutConfig, skipCertVerify bool) *http.Client {
-	tlsConfig := api.NewTLSConfig()
-	tlsConfig.InsecureSkipVerify = skipCertVerify
-
-	tr := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   netConf.DialTimeout,
-			KeepAlive: netConf.Keepalive,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       tlsConfig,
-	}
+// Close releases the transport of the writer. Connections of the
+// transport are closed once no other writer uses it.
+func (w *HTTPSWriter) Close() error {
+	w.closeOnce.Do(func() {
+		if w.release != nil {
+			w.release()
+		}
+	})
 
-	return &http.Client{
-		Transport: tr,
-		Timeout:   60 * time.Second,
-	}
+	return nil
 }
@@ -1,10 +1,15 @@
 package egress_test
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"time"
 
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 	"code.cloudfoundry.org/rfc5424"
@@ -94,6 +99,80 @@ var _ = Describe("HTTPWriter", func() {
 		Expect(err.Error()).ToNot(ContainSubstring("password"))
 	})
 
+	It("adds the drain headers to each request", func() {
+		drain := newMockOKDrain()
+
+		b := buildURLBinding(drain.URL, "test-app-id", "test-hostname")
+		b.Headers = http.Header{
+			"Authorization": []string{"Bearer some-token"},
+			"X-Api-Key":     []string{"some-key"},
+		}
+
+		writer := egress.NewHTTPSWriter(
+			b,
+			netConf,
+			true,
+			&testhelper.SpyMetric{},
+		)
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(drain.headers).To(HaveLen(1))
+		Expect(drain.headers[0].Get("Authorization")).To(Equal("Bearer some-token"))
+		Expect(drain.headers[0].Get("X-Api-Key")).To(Equal("some-key"))
+		Expect(drain.headers[0].Get("Content-Type")).To(Equal("text/plain"))
+	})
+
+	It("signs each request when a signing secret is set", func() {
+		drain := newMockOKDrain()
+
+		b := buildURLBinding(drain.URL, "test-app-id", "test-hostname")
+		b.SigningSecret = []byte("some-secret")
+
+		writer := egress.NewHTTPSWriter(
+			b,
+			netConf,
+			true,
+			&testhelper.SpyMetric{},
+		)
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(drain.headers).To(HaveLen(1))
+		ts := drain.headers[0].Get("X-Syslog-Timestamp")
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Unix(sec, 0)).To(BeTemporally("~", time.Now(), 5*time.Second))
+
+		Expect(drain.bodies).To(HaveLen(1))
+		mac := hmac.New(sha256.New, []byte("some-secret"))
+		mac.Write([]byte(ts + "."))
+		mac.Write(drain.bodies[0])
+		Expect(drain.headers[0].Get("X-Syslog-Signature")).To(Equal(
+			"sha256=" + hex.EncodeToString(mac.Sum(nil)),
+		))
+	})
+
+	It("does not sign requests without a signing secret", func() {
+		drain := newMockOKDrain()
+
+		writer := egress.NewHTTPSWriter(
+			buildURLBinding(drain.URL, "test-app-id", "test-hostname"),
+			netConf,
+			true,
+			&testhelper.SpyMetric{},
+		)
+
+		env := buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(drain.headers).To(HaveLen(1))
+		Expect(drain.headers[0]).ToNot(HaveKey("X-Syslog-Signature"))
+		Expect(drain.headers[0]).ToNot(HaveKey("X-Syslog-Timestamp"))
+	})
+
 	It("writes syslog formatted messages to http drain", func() {
 		drain := newMockOKDrain()
 
@@ -279,6 +358,8 @@ var _ = Describe("HTTPWriter", func() {
 type SpyDrain struct {
 	*httptest.Server
 	messages []*rfc5424.Message
+	headers  []http.Header
+	bodies   [][]byte
 }
 
 func newMockOKDrain() *SpyDrain {
@@ -302,6 +383,8 @@ func newMockDrain(status int) *SpyDrain {
 		Expect(err).ToNot(HaveOccurred())
 
 		drain.messages = append(drain.messages, message)
+		drain.headers = append(drain.headers, r.Header)
+		drain.bodies = append(drain.bodies, body)
 		w.WriteHeader(status)
 	})
 	server := httptest.NewTLSServer(handler)
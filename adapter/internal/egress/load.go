@@ -0,0 +1,223 @@
+package egress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+)
+
+// LoadMeter tracks the envelope rates and buffer fill of every binding and
+// the open drain connections of the adapter so that the scheduler can place
+// bindings by load. Rates are averaged over at least the sample window.
+type LoadMeter struct {
+	maxBindings int
+	window      time.Duration
+	pool        *TransportPool
+
+	mu       sync.Mutex
+	counters map[v1.Binding]*LoadCounter
+	sampled  time.Time
+}
+
+// NewLoadMeter returns a LoadMeter. The maximum number of bindings is
+// reported as the capacity of the adapter. Connections of the transport
+// pool are counted along with syslog and syslog-tls connections. The pool
+// may be nil.
+func NewLoadMeter(maxBindings int, window time.Duration, pool *TransportPool) *LoadMeter {
+	return &LoadMeter{
+		maxBindings: maxBindings,
+		window:      window,
+		pool:        pool,
+		counters:    make(map[v1.Binding]*LoadCounter),
+		sampled:     time.Now(),
+	}
+}
+
+// Counter returns the counter of the binding. A nil LoadMeter returns a nil
+// counter which discards all counts.
+func (m *LoadMeter) Counter(b *v1.Binding) *LoadCounter {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[*b]
+	if !ok {
+		c = &LoadCounter{}
+		m.counters[*b] = c
+	}
+	c.stale = false
+
+	return c
+}
+
+// Load reports the load of the adapter for the given bindings. Counters of
+// bindings that are missing from two consecutive reports are discarded.
+func (m *LoadMeter) Load(bindings []*v1.Binding) *v1.GetLoadResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if elapsed := now.Sub(m.sampled); elapsed >= m.window {
+		for _, c := range m.counters {
+			c.sample(elapsed)
+		}
+		m.sampled = now
+	}
+
+	resp := &v1.GetLoadResponse{
+		Connections: int64(openConns.total() + m.pool.openConnections()),
+		MaxBindings: int64(m.maxBindings),
+	}
+
+	active := make(map[v1.Binding]bool, len(bindings))
+	for _, b := range bindings {
+		active[*b] = true
+
+		bl := &v1.BindingLoad{Binding: b}
+		if c, ok := m.counters[*b]; ok {
+			bl.IngressRate = c.ingressRate
+			bl.EgressRate = c.egressRate
+			bl.BufferFill = c.fill()
+			bl.Streaming = atomic.LoadInt32(&c.streaming) > 0
+		}
+
+		resp.IngressRate += bl.IngressRate
+		resp.EgressRate += bl.EgressRate
+		resp.BufferFill += bl.BufferFill
+		resp.Bindings = append(resp.Bindings, bl)
+	}
+
+	if len(bindings) > 0 {
+		resp.BufferFill /= float64(len(bindings))
+	}
+
+	for b, c := range m.counters {
+		if active[b] {
+			continue
+		}
+
+		if c.stale {
+			delete(m.counters, b)
+			continue
+		}
+		c.stale = true
+	}
+
+	return resp
+}
+
+// LoadCounter counts the envelopes a binding receives and writes to its
+// drain. All methods of a nil LoadCounter are no-ops.
+type LoadCounter struct {
+	ingress   uint64
+	egress    uint64
+	streaming int32
+
+	mu     sync.Mutex
+	buffer *DiodeWriter
+
+	// The remaining fields are guarded by the LoadMeter.
+	lastIngress uint64
+	lastEgress  uint64
+	ingressRate float64
+	egressRate  float64
+	stale       bool
+}
+
+// AddIngress counts envelopes received for the binding.
+func (c *LoadCounter) AddIngress(n int) {
+	if c == nil {
+		return
+	}
+
+	atomic.AddUint64(&c.ingress, uint64(n))
+}
+
+// SetStreaming records that a log stream for the binding was opened or
+// closed. The scheduler waits for the stream of a replacement before it
+// removes a binding from an adapter that is draining.
+func (c *LoadCounter) SetStreaming(streaming bool) {
+	if c == nil {
+		return
+	}
+
+	if streaming {
+		atomic.AddInt32(&c.streaming, 1)
+		return
+	}
+	atomic.AddInt32(&c.streaming, -1)
+}
+
+func (c *LoadCounter) addEgress(n int) {
+	if c == nil {
+		return
+	}
+
+	atomic.AddUint64(&c.egress, uint64(n))
+}
+
+// setBuffer replaces the buffer whose fill is reported. Each connect of a
+// binding creates a new buffer.
+func (c *LoadCounter) setBuffer(dw *DiodeWriter) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.buffer = dw
+}
+
+func (c *LoadCounter) fill() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.buffer == nil {
+		return 0
+	}
+
+	return c.buffer.Fill()
+}
+
+func (c *LoadCounter) sample(elapsed time.Duration) {
+	ingress := atomic.LoadUint64(&c.ingress)
+	egress := atomic.LoadUint64(&c.egress)
+
+	c.ingressRate = float64(ingress-c.lastIngress) / elapsed.Seconds()
+	c.egressRate = float64(egress-c.lastEgress) / elapsed.Seconds()
+	c.lastIngress = ingress
+	c.lastEgress = egress
+}
+
+// wrap counts the envelopes the writer writes successfully.
+func (c *LoadCounter) wrap(w WriteCloser) WriteCloser {
+	if c == nil {
+		return w
+	}
+
+	return &countingWriter{
+		WriteCloser: w,
+		counter:     c,
+	}
+}
+
+type countingWriter struct {
+	WriteCloser
+	counter *LoadCounter
+}
+
+func (w *countingWriter) Write(env *loggregator_v2.Envelope) error {
+	err := w.WriteCloser.Write(env)
+	if err == nil {
+		w.counter.addEgress(1)
+	}
+
+	return err
+}
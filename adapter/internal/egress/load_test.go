@@ -0,0 +1,116 @@
+package egress_test
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoadMeter", func() {
+	var (
+		meter   *egress.LoadMeter
+		binding *v1.Binding
+	)
+
+	BeforeEach(func() {
+		meter = egress.NewLoadMeter(100, 10*time.Millisecond, nil)
+		binding = &v1.Binding{
+			AppId: "some-app-id",
+			Drain: "foo://some-drain",
+		}
+	})
+
+	connect := func(duration time.Duration) egress.Writer {
+		constructor := func(
+			*egress.URLBinding,
+			egress.NetworkTimeoutConfig,
+			bool,
+			pulseemitter.CounterMetric,
+		) egress.WriteCloser {
+			return &SleepWriterCloser{
+				metric:   nullMetric{},
+				duration: duration,
+			}
+		}
+
+		connector := egress.NewSyslogConnector(
+			egress.NetworkTimeoutConfig{},
+			false,
+			&SpyWaitGroup{},
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"foo": constructor,
+			}),
+			egress.WithLoadMeter(meter),
+		)
+
+		writer, err := connector.Connect(context.Background(), binding)
+		Expect(err).ToNot(HaveOccurred())
+
+		return writer
+	}
+
+	It("reports the capacity and the ingress rate of each binding", func() {
+		meter.Counter(binding).AddIngress(50)
+		time.Sleep(20 * time.Millisecond)
+
+		resp := meter.Load([]*v1.Binding{binding})
+
+		Expect(resp.MaxBindings).To(Equal(int64(100)))
+		Expect(resp.Bindings).To(HaveLen(1))
+		Expect(resp.Bindings[0].Binding).To(Equal(binding))
+		Expect(resp.Bindings[0].IngressRate).To(BeNumerically(">", 0))
+		Expect(resp.IngressRate).To(Equal(resp.Bindings[0].IngressRate))
+	})
+
+	It("reports the egress rate of envelopes written to the drain", func() {
+		writer := connect(0)
+		for i := 0; i < 10; i++ {
+			writer.Write(&loggregator_v2.Envelope{SourceId: "some-app-id"})
+		}
+
+		Eventually(func() float64 {
+			return meter.Load([]*v1.Binding{binding}).EgressRate
+		}).Should(BeNumerically(">", 0))
+	})
+
+	It("reports the fill of the binding buffer", func() {
+		writer := connect(time.Hour)
+		for i := 0; i < 1000; i++ {
+			writer.Write(&loggregator_v2.Envelope{SourceId: "some-app-id"})
+		}
+
+		resp := meter.Load([]*v1.Binding{binding})
+
+		Expect(resp.BufferFill).To(BeNumerically("~", 0.1, 0.01))
+		Expect(resp.Bindings[0].BufferFill).To(Equal(resp.BufferFill))
+	})
+
+	It("reports whether the binding is streaming", func() {
+		counter := meter.Counter(binding)
+
+		counter.SetStreaming(true)
+		Expect(meter.Load([]*v1.Binding{binding}).Bindings[0].Streaming).To(BeTrue())
+
+		counter.SetStreaming(false)
+		Expect(meter.Load([]*v1.Binding{binding}).Bindings[0].Streaming).To(BeFalse())
+	})
+
+	It("discards counters of bindings that are no longer reported", func() {
+		counter := meter.Counter(binding)
+
+		meter.Load(nil)
+		Expect(meter.Counter(binding)).To(BeIdenticalTo(counter))
+
+		meter.Load(nil)
+		meter.Load(nil)
+		Expect(meter.Counter(binding)).ToNot(BeIdenticalTo(counter))
+	})
+})
@@ -0,0 +1,66 @@
+package egress
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"strconv"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/rfc5424"
+)
+
+const messageIDStructuredDataID = "dedup@47450"
+
+// messageIDs adds an ID to the structured data of each message so receivers
+// can drop the duplicates written by the replicas of a binding. The ID is a
+// hash of the formatted message, so every adapter that writes the same
+// envelope to the drain writes the same ID. Messages that are split into
+// fragments share the ID of the original message.
+type messageIDs struct {
+	enabled bool
+}
+
+// newMessageIDs enables message IDs for drains that request more than one
+// replica with the replicas query parameter, or for all drains if enabled
+// is true.
+func newMessageIDs(b *URLBinding, enabled bool) messageIDs {
+	if !enabled {
+		n, err := strconv.Atoi(b.Options.Get("replicas"))
+		enabled = err == nil && n > 1
+	}
+
+	return messageIDs{enabled: enabled}
+}
+
+// messageIDWriter adds message IDs to the messages.
+type messageIDWriter struct {
+	next messageWriter
+}
+
+func (w *messageIDWriter) writeMessages(env *loggregator_v2.Envelope, msgs []rfc5424.Message) error {
+	annotateMessageIDs(msgs)
+
+	return w.next.writeMessages(env, msgs)
+}
+
+func annotateMessageIDs(msgs []rfc5424.Message) {
+	for i := range msgs {
+		b, err := msgs[i].MarshalBinary()
+		if err != nil {
+			continue
+		}
+
+		h := fnv.New128a()
+		h.Write(b)
+
+		msgs[i].StructuredData = append(msgs[i].StructuredData, rfc5424.StructuredData{
+			ID: messageIDStructuredDataID,
+			Parameters: []rfc5424.SDParam{
+				{
+					Name:  "id",
+					Value: hex.EncodeToString(h.Sum(nil)),
+				},
+			},
+		})
+	}
+}
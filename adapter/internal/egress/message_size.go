@@ -0,0 +1,195 @@
+package egress
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+	"unicode/utf8"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/rfc5424"
+)
+
+const (
+	fragmentStructuredDataID = "fragment@47450"
+	truncatedMarker          = "[truncated]"
+)
+
+// sizeLimit enforces the max-message-size query parameter of a drain URL.
+// Messages whose RFC 5424 encoding exceeds the size are truncated with a
+// marker, or with max-message-mode=split, split into fragments that carry
+// their 1-based index and the fragment count in structured data.
+type sizeLimit struct {
+	max   int
+	split bool
+}
+
+func newSizeLimit(b *URLBinding) sizeLimit {
+	query := b.Options
+	raw := query.Get("max-message-size")
+	if raw == "" {
+		return sizeLimit{}
+	}
+
+	max, err := strconv.Atoi(raw)
+	if err != nil || max <= 0 {
+		log.Printf("invalid max message size %q for syslog drain %s", raw, b.drainHost())
+		return sizeLimit{}
+	}
+
+	l := sizeLimit{max: max}
+	switch mode := query.Get("max-message-mode"); mode {
+	case "", "truncate":
+	case "split":
+		l.split = true
+	default:
+		log.Printf("unknown max message mode %q for syslog drain %s", mode, b.drainHost())
+	}
+
+	return l
+}
+
+func (l sizeLimit) enabled() bool {
+	return l.max > 0
+}
+
+// sizeLimitWriter truncates or splits oversized messages.
+type sizeLimitWriter struct {
+	next  messageWriter
+	limit sizeLimit
+}
+
+func (w *sizeLimitWriter) writeMessages(env *loggregator_v2.Envelope, msgs []rfc5424.Message) error {
+	return w.next.writeMessages(env, w.limit.apply(msgs))
+}
+
+// apply returns the messages with oversized messages truncated or split.
+// Messages are left untouched if their headers alone exceed the limit.
+func (l sizeLimit) apply(msgs []rfc5424.Message) []rfc5424.Message {
+	res := make([]rfc5424.Message, 0, len(msgs))
+	for _, m := range msgs {
+		if messageSize(m) <= l.max {
+			res = append(res, m)
+			continue
+		}
+
+		if l.split {
+			res = append(res, l.fragment(m)...)
+			continue
+		}
+
+		res = append(res, l.truncate(m))
+	}
+
+	return res
+}
+
+func (l sizeLimit) truncate(m rfc5424.Message) rfc5424.Message {
+	body := bytes.TrimSuffix(m.Message, []byte("\n"))
+	overhead := messageSize(m) - len(m.Message)
+
+	n := l.max - overhead - len(truncatedMarker) - 1
+	if n <= 0 {
+		return m
+	}
+
+	msg := make([]byte, 0, l.max-overhead)
+	msg = append(msg, cutRunes(body, n)...)
+	msg = append(msg, truncatedMarker...)
+	m.Message = append(msg, '\n')
+
+	return m
+}
+
+func (l sizeLimit) fragment(m rfc5424.Message) []rfc5424.Message {
+	body := bytes.TrimSuffix(m.Message, []byte("\n"))
+
+	// The size of the fragment structured data depends on the number of
+	// fragments. Grow the estimate until all fragments fit.
+	var chunks [][]byte
+	for count := 1; ; {
+		overhead := messageSize(withFragment(m, count, count, nil))
+		if overhead >= l.max {
+			return []rfc5424.Message{m}
+		}
+
+		chunks = splitRunes(body, l.max-overhead)
+		if len(chunks) <= count {
+			break
+		}
+		count = len(chunks)
+	}
+
+	fragments := make([]rfc5424.Message, 0, len(chunks))
+	for i, c := range chunks {
+		fragments = append(fragments, withFragment(m, i+1, len(chunks), c))
+	}
+
+	return fragments
+}
+
+// withFragment returns a copy of the message with the given body and the
+// fragment structured data.
+func withFragment(m rfc5424.Message, index, count int, body []byte) rfc5424.Message {
+	sd := make([]rfc5424.StructuredData, 0, len(m.StructuredData)+1)
+	sd = append(sd, m.StructuredData...)
+	m.StructuredData = append(sd, rfc5424.StructuredData{
+		ID: fragmentStructuredDataID,
+		Parameters: []rfc5424.SDParam{
+			{
+				Name:  "index",
+				Value: strconv.Itoa(index),
+			},
+			{
+				Name:  "count",
+				Value: strconv.Itoa(count),
+			},
+		},
+	})
+
+	msg := make([]byte, 0, len(body)+1)
+	msg = append(msg, body...)
+	m.Message = append(msg, '\n')
+
+	return m
+}
+
+func messageSize(m rfc5424.Message) int {
+	b, err := m.MarshalBinary()
+	if err != nil {
+		return 0
+	}
+
+	return len(b)
+}
+
+// cutRunes returns at most n bytes of b without splitting a UTF-8 encoded
+// rune.
+func cutRunes(b []byte, n int) []byte {
+	if len(b) <= n {
+		return b
+	}
+
+	cut := n
+	for cut > 0 && !utf8.RuneStart(b[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		cut = n
+	}
+
+	return b[:cut]
+}
+
+// splitRunes splits b into chunks of at most n bytes without splitting UTF-8
+// encoded runes.
+func splitRunes(b []byte, n int) [][]byte {
+	var chunks [][]byte
+	for len(b) > n {
+		c := cutRunes(b, n)
+		chunks = append(chunks, c)
+		b = b[len(c):]
+	}
+
+	return append(chunks, b)
+}
@@ -0,0 +1,49 @@
+package egress
+
+import (
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/rfc5424"
+)
+
+// messageWriter writes the syslog messages formatted from an envelope. The
+// envelope is passed along so stages can make decisions based on it.
+type messageWriter interface {
+	writeMessages(env *loggregator_v2.Envelope, msgs []rfc5424.Message) error
+}
+
+// messageSink writes a single message to a drain.
+type messageSink func(msg rfc5424.Message) error
+
+// writeMessages writes the messages in order and stops at the first error.
+func (f messageSink) writeMessages(env *loggregator_v2.Envelope, msgs []rfc5424.Message) error {
+	for _, msg := range msgs {
+		if err := f(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newMessageWriter returns a messageWriter that samples the messages,
+// adds message IDs and enforces the maximum message size as requested by
+// the binding before the messages are written to the sink. Stages the
+// binding does not use are left out.
+func newMessageWriter(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	sink messageSink,
+) messageWriter {
+	var w messageWriter = sink
+	if l := newSizeLimit(binding); l.enabled() {
+		w = &sizeLimitWriter{next: w, limit: l}
+	}
+	if ids := newMessageIDs(binding, netConf.MessageIDs); ids.enabled {
+		w = &messageIDWriter{next: w}
+	}
+	if s := newSampler(binding); s.enabled() {
+		w = &samplingWriter{next: w, sampler: s}
+	}
+
+	return w
+}
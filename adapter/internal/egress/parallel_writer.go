@@ -0,0 +1,152 @@
+package egress
+
+import (
+	"errors"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"sync"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"golang.org/x/net/context"
+)
+
+// errParallelWriterClosed is returned by writes to a closed ParallelWriter.
+var errParallelWriterClosed = errors.New("parallel writer is closed")
+
+// maxInFlight caps the in-flight query parameter of a drain URL.
+const maxInFlight = 64
+
+// inFlight returns the number of requests a drain allows in flight and
+// whether the order of envelopes from each source instance has to be kept.
+// They are set with the in-flight and ordering=instance query parameters.
+func inFlight(b *URLBinding) (int, bool) {
+	query := b.Options
+	raw := query.Get("in-flight")
+	if raw == "" {
+		return 1, false
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		log.Printf("invalid in-flight %q for syslog drain %s", raw, b.drainHost())
+		return 1, false
+	}
+
+	if n > maxInFlight {
+		n = maxInFlight
+	}
+
+	return n, query.Get("ordering") == "instance"
+}
+
+// ParallelWriter writes envelopes with several writers at once. Each writer
+// writes one envelope at a time, so the number of writers bounds the
+// envelopes in flight. When ordered, all envelopes of a source instance are
+// written by the same writer and keep their order.
+type ParallelWriter struct {
+	ctx     context.Context
+	writers []WriteCloser
+	queues  []chan *loggregator_v2.Envelope
+	ordered bool
+
+	// done is closed by Close. The queues are never closed, so that writes
+	// that race with Close return an error instead of panicking.
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// NewParallelWriter starts a goroutine for each writer. Write blocks while
+// all writers are busy.
+func NewParallelWriter(
+	ctx context.Context,
+	writers []WriteCloser,
+	ordered bool,
+) *ParallelWriter {
+	p := &ParallelWriter{
+		ctx:     ctx,
+		writers: writers,
+		ordered: ordered,
+		done:    make(chan struct{}),
+	}
+
+	shared := make(chan *loggregator_v2.Envelope)
+	for _, w := range writers {
+		q := shared
+		if ordered {
+			q = make(chan *loggregator_v2.Envelope)
+		}
+		p.queues = append(p.queues, q)
+
+		p.wg.Add(1)
+		go p.run(w, q)
+	}
+
+	return p
+}
+
+// Write hands the envelope to the next available writer, or with ordering
+// to the writer of its source instance. It returns an error once the
+// writer is closed.
+func (p *ParallelWriter) Write(env *loggregator_v2.Envelope) error {
+	q := p.queues[0]
+	if p.ordered {
+		q = p.queues[instanceHash(env)%uint32(len(p.queues))]
+	}
+
+	select {
+	case <-p.done:
+		return errParallelWriterClosed
+	default:
+	}
+
+	select {
+	case q <- env:
+		return nil
+	case <-p.done:
+		return errParallelWriterClosed
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
+}
+
+// Close waits for the writers to finish their envelopes and closes them.
+func (p *ParallelWriter) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	p.wg.Wait()
+
+	var err error
+	for _, w := range p.writers {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+func (p *ParallelWriter) run(w WriteCloser, q chan *loggregator_v2.Envelope) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case env := <-q:
+			// Errors are ignored like in the DiodeWriter. Writers are
+			// expected to retry on their own.
+			_ = w.Write(env)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func instanceHash(env *loggregator_v2.Envelope) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(env.GetTags()["source_type"]))
+	h.Write([]byte(env.GetInstanceId()))
+
+	return h.Sum32()
+}
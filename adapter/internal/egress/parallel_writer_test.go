@@ -0,0 +1,268 @@
+package egress_test
+
+import (
+	"strconv"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParallelWriter", func() {
+	var (
+		ctx     context.Context
+		cancel  func()
+		writers []*spyGatedWriter
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		writers = nil
+		for i := 0; i < 3; i++ {
+			writers = append(writers, newSpyGatedWriter())
+		}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	var writeClosers = func() []egress.WriteCloser {
+		var wcs []egress.WriteCloser
+		for _, w := range writers {
+			wcs = append(wcs, w)
+		}
+		return wcs
+	}
+
+	It("writes as many envelopes at once as it has writers", func() {
+		p := egress.NewParallelWriter(ctx, writeClosers(), false)
+
+		for i := 0; i < 3; i++ {
+			Expect(p.Write(&loggregator_v2.Envelope{})).To(Succeed())
+		}
+		for _, w := range writers {
+			Eventually(w.started).Should(Equal(1))
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Write(&loggregator_v2.Envelope{})
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+
+		writers[0].release <- struct{}{}
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("keeps the order of envelopes from each source instance", func() {
+		p := egress.NewParallelWriter(ctx, writeClosers(), true)
+		for _, w := range writers {
+			close(w.release)
+		}
+
+		for i := 0; i < 30; i++ {
+			env := buildLogEnvelope("APP", string(rune('a'+i%5)), "", loggregator_v2.Log_OUT)
+			env.Timestamp = int64(i + 1)
+			Expect(p.Write(env)).To(Succeed())
+		}
+		Expect(p.Close()).To(Succeed())
+
+		for _, w := range writers {
+			last := make(map[string]int64)
+			for _, env := range w.written() {
+				Expect(env.Timestamp).To(BeNumerically(">", last[env.InstanceId]))
+				last[env.InstanceId] = env.Timestamp
+
+				for _, other := range writers {
+					if other != w {
+						Expect(other.instances()).ToNot(HaveKey(env.InstanceId))
+					}
+				}
+			}
+		}
+	})
+
+	It("returns an error when the context is done and all writers are busy", func() {
+		p := egress.NewParallelWriter(ctx, writeClosers(), false)
+		for i := 0; i < 3; i++ {
+			Expect(p.Write(&loggregator_v2.Envelope{})).To(Succeed())
+		}
+
+		cancel()
+
+		Expect(p.Write(&loggregator_v2.Envelope{})).ToNot(Succeed())
+	})
+
+	It("closes all writers", func() {
+		p := egress.NewParallelWriter(ctx, writeClosers(), false)
+
+		Expect(p.Close()).To(Succeed())
+
+		for _, w := range writers {
+			Expect(w.closed()).To(BeTrue())
+		}
+	})
+
+	It("returns an error for writes after it is closed", func() {
+		p := egress.NewParallelWriter(ctx, writeClosers(), false)
+		Expect(p.Close()).To(Succeed())
+
+		Expect(p.Write(&loggregator_v2.Envelope{})).To(MatchError("parallel writer is closed"))
+	})
+
+	It("does not panic when writes race with close", func() {
+		for _, w := range writers {
+			close(w.release)
+		}
+		p := egress.NewParallelWriter(ctx, writeClosers(), true)
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+
+			for i := 0; i < 100; i++ {
+				p.Write(&loggregator_v2.Envelope{InstanceId: strconv.Itoa(i)})
+			}
+		}()
+
+		Expect(p.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("SyslogConnector in-flight requests", func() {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	var constructor = func(
+		*egress.URLBinding,
+		egress.NetworkTimeoutConfig,
+		bool,
+		pulseemitter.CounterMetric,
+	) egress.WriteCloser {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+
+		return &SleepWriterCloser{metric: nullMetric{}}
+	}
+
+	BeforeEach(func() {
+		calls = 0
+	})
+
+	var connect = func(drain string, expected int) {
+		connector := egress.NewSyslogConnector(
+			egress.NetworkTimeoutConfig{},
+			true,
+			&SpyWaitGroup{},
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"https":  constructor,
+				"syslog": constructor,
+			}),
+		)
+
+		_, err := connector.Connect(context.Background(), &v1.Binding{
+			AppId: "app-id",
+			Drain: drain,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(calls).To(Equal(expected))
+	}
+
+	It("creates a writer for each request in flight", func() {
+		connect("https://some-host?in-flight=4", 4)
+	})
+
+	It("creates a single writer by default", func() {
+		connect("https://some-host", 1)
+	})
+
+	It("ignores in-flight for syslog drains", func() {
+		connect("syslog://some-host:514?in-flight=4", 1)
+	})
+})
+
+type spyGatedWriter struct {
+	mu       sync.Mutex
+	release  chan struct{}
+	started_ int
+	written_ []*loggregator_v2.Envelope
+	closed_  bool
+}
+
+func newSpyGatedWriter() *spyGatedWriter {
+	return &spyGatedWriter{
+		release: make(chan struct{}),
+	}
+}
+
+func (s *spyGatedWriter) Write(env *loggregator_v2.Envelope) error {
+	s.mu.Lock()
+	s.started_++
+	s.mu.Unlock()
+
+	<-s.release
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written_ = append(s.written_, env)
+
+	return nil
+}
+
+func (s *spyGatedWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed_ = true
+
+	return nil
+}
+
+func (s *spyGatedWriter) started() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.started_
+}
+
+func (s *spyGatedWriter) written() []*loggregator_v2.Envelope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.written_
+}
+
+func (s *spyGatedWriter) instances() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instances := make(map[string]bool)
+	for _, env := range s.written_ {
+		instances[env.InstanceId] = true
+	}
+
+	return instances
+}
+
+func (s *spyGatedWriter) closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed_
+}
@@ -0,0 +1,263 @@
+package egress
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// Proxy routes the connections of an adapter to its drains through egress
+// proxies. HTTPS drains use the HTTPS proxy. Syslog and syslog-tls drains
+// are tunneled through the syslog proxy with HTTP CONNECT or SOCKS5. Drains
+// that match the no proxy rules connect directly. The rules follow the
+// semantics of the NO_PROXY environment variable. Drains on localhost or a
+// loopback address always connect directly, as do drains with the proxy
+// query parameter set to none.
+type Proxy struct {
+	httpsProxy  func(*url.URL) (*url.URL, error)
+	syslogProxy func(*url.URL) (*url.URL, error)
+}
+
+// NewProxy returns a Proxy. Empty proxy URLs disable proxying for the
+// respective drains. The syslog proxy has to use the http or socks5 scheme
+// and defaults to port 80 or 1080 respectively.
+func NewProxy(httpsProxy, syslogProxy, noProxy string) (*Proxy, error) {
+	if _, err := parseProxyURL(httpsProxy); err != nil {
+		return nil, err
+	}
+
+	u, err := parseProxyURL(syslogProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	if u != nil && u.Scheme != "http" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("unsupported syslog proxy scheme %s", u.Scheme)
+	}
+
+	return &Proxy{
+		httpsProxy: (&httpproxy.Config{
+			HTTPSProxy: httpsProxy,
+			NoProxy:    noProxy,
+		}).ProxyFunc(),
+		syslogProxy: (&httpproxy.Config{
+			HTTPSProxy: syslogProxy,
+			NoProxy:    noProxy,
+		}).ProxyFunc(),
+	}, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", redactProxyURL(raw))
+	}
+
+	return u, nil
+}
+
+// redactProxyURL removes the password of a proxy URL so it can be logged.
+func redactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+
+	return u.Redacted()
+}
+
+// forDrain returns the proxy for the connections to the drain of the
+// binding. It is nil for drains that opt out of proxying with proxy=none.
+func (p *Proxy) forDrain(b *URLBinding) *Proxy {
+	switch mode := b.Options.Get("proxy"); mode {
+	case "":
+		return p
+	case "none":
+		return nil
+	default:
+		log.Printf("unknown proxy mode %q for syslog drain %s", mode, b.drainHost())
+		return p
+	}
+}
+
+// https returns the proxy function for the transports of HTTPS drains. A nil
+// Proxy does not use a proxy.
+func (p *Proxy) https() func(*http.Request) (*url.URL, error) {
+	if p == nil {
+		return nil
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		return p.httpsProxy(req.URL)
+	}
+}
+
+// dial connects to the address of a syslog drain, through the syslog proxy
+// unless the address or the server name are excluded from proxying. A nil
+// Proxy connects directly.
+func (p *Proxy) dial(dialer *net.Dialer, addr, serverName string) (net.Conn, error) {
+	if p == nil {
+		return dialer.Dial("tcp", addr)
+	}
+
+	proxyURL, err := p.syslogProxyFor(addr, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case proxyURL == nil:
+		return dialer.Dial("tcp", addr)
+	case proxyURL.Scheme == "socks5":
+		return dialSOCKS5(dialer, proxyURL, addr)
+	default:
+		return dialConnect(dialer, proxyURL, addr)
+	}
+}
+
+func (p *Proxy) syslogProxyFor(addr, serverName string) (*url.URL, error) {
+	proxyURL, err := p.syslogProxy(&url.URL{Scheme: "https", Host: addr})
+	if err != nil || proxyURL == nil {
+		return nil, err
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil || serverName == "" {
+		return proxyURL, nil
+	}
+
+	// Addresses of resolved drains are IPs, so the rules are also matched
+	// against the host name of the drain.
+	return p.syslogProxy(&url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(serverName, port),
+	})
+}
+
+func dialSOCKS5(dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{
+			User:     proxyURL.User.Username(),
+			Password: password,
+		}
+	}
+
+	d, err := proxy.SOCKS5("tcp", proxyAddr(proxyURL), auth, deadlineDialer{dialer})
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := d.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+// deadlineDialer sets the dial timeout of the dialer as the deadline of the
+// connections it dials, so that the SOCKS5 handshake cannot block forever.
+type deadlineDialer struct {
+	dialer *net.Dialer
+}
+
+func (d deadlineDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := d.dialer.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(d.dialer.Timeout))
+	}
+
+	return conn, nil
+}
+
+// proxyAddr returns the address of the proxy. Proxy URLs without a port use
+// the default port of their scheme.
+func proxyAddr(proxyURL *url.URL) string {
+	if proxyURL.Port() != "" {
+		return proxyURL.Host
+	}
+
+	port := "80"
+	switch proxyURL.Scheme {
+	case "socks5":
+		port = "1080"
+	case "https":
+		port = "443"
+	}
+
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// dialConnect tunnels a connection to the address through an HTTP proxy
+// with the CONNECT method.
+func dialConnect(dialer *net.Dialer, proxyURL *url.URL, addr string) (net.Conn, error) {
+	conn, err := dialer.Dial("tcp", proxyAddr(proxyURL))
+	if err != nil {
+		return nil, err
+	}
+
+	if dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	if u := proxyURL.User; u != nil {
+		password, _ := u.Password()
+		credentials := base64.StdEncoding.EncodeToString(
+			[]byte(u.Username() + ":" + password),
+		)
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy responded to CONNECT with %s", resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		conn.Close()
+		return nil, fmt.Errorf("proxy sent data before the tunnel was established")
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
@@ -0,0 +1,458 @@
+package egress_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/test_util"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Proxy", func() {
+	var (
+		drain   net.Listener
+		env     = buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+		netConf egress.NetworkTimeoutConfig
+	)
+
+	BeforeEach(func() {
+		var err error
+		drain, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		netConf = egress.NetworkTimeoutConfig{
+			WriteTimeout: time.Second,
+			DialTimeout:  time.Second,
+		}
+	})
+
+	AfterEach(func() {
+		drain.Close()
+	})
+
+	var readMessage = func(l net.Listener) string {
+		conn, err := l.Accept()
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		msg, err := bufio.NewReader(conn).ReadString('\n')
+		Expect(err).ToNot(HaveOccurred())
+
+		return msg
+	}
+
+	var buildBinding = func(drainURL string) *egress.URLBinding {
+		u, _ := url.Parse(drainURL)
+
+		return &egress.URLBinding{
+			AppID:    "test-app-id",
+			Hostname: "test-hostname",
+			URL:      u,
+		}
+	}
+
+	It("tunnels syslog drains through an HTTP proxy", func() {
+		proxy := newSpyConnectProxy(drain.Addr().String())
+		defer proxy.Close()
+
+		netConf.Proxy, _ = egress.NewProxy("", "http://user:pass@"+proxy.addr(), "")
+		writer := egress.NewTCPWriter(
+			buildBinding("syslog://drain.example.com:514"),
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readMessage(drain)).To(HaveSuffix("just a test\n"))
+		Expect(proxy.targets()).To(ConsistOf("drain.example.com:514"))
+		Expect(proxy.authorization()).To(ConsistOf("Basic dXNlcjpwYXNz"))
+	})
+
+	It("tunnels syslog drains through a SOCKS5 proxy", func() {
+		proxy := newSpySOCKS5Proxy(drain.Addr().String())
+		defer proxy.Close()
+
+		netConf.Proxy, _ = egress.NewProxy("", "socks5://"+proxy.addr(), "")
+		writer := egress.NewTCPWriter(
+			buildBinding("syslog://drain.example.com:514"),
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readMessage(drain)).To(HaveSuffix("just a test\n"))
+		Expect(proxy.targets()).To(ConsistOf("drain.example.com:514"))
+	})
+
+	It("connects to SOCKS5 proxies without a port on port 1080", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:1080")
+		if err != nil {
+			Skip("port 1080 is not available")
+		}
+		proxy := newSpyProxyOn(l, drain.Addr().String(), socks5Handshake)
+		defer proxy.Close()
+
+		netConf.Proxy, _ = egress.NewProxy("", "socks5://127.0.0.1", "")
+		writer := egress.NewTCPWriter(
+			buildBinding("syslog://drain.example.com:514"),
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readMessage(drain)).To(HaveSuffix("just a test\n"))
+		Expect(proxy.targets()).To(ConsistOf("drain.example.com:514"))
+	})
+
+	It("connects to HTTP proxies without a port on port 80", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:80")
+		if err != nil {
+			Skip("port 80 is not available")
+		}
+		proxy := newSpyProxyOn(l, drain.Addr().String(), connectHandshake)
+		defer proxy.Close()
+
+		netConf.Proxy, _ = egress.NewProxy("", "http://127.0.0.1", "")
+		writer := egress.NewTCPWriter(
+			buildBinding("syslog://drain.example.com:514"),
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		Expect(writer.Write(env)).To(Succeed())
+
+		Expect(readMessage(drain)).To(HaveSuffix("just a test\n"))
+		Expect(proxy.targets()).To(ConsistOf("drain.example.com:514"))
+	})
+
+	It("times out SOCKS5 proxies that do not complete the handshake", func() {
+		proxy := newSpyProxy(drain.Addr().String(), func(p *spyProxy, conn net.Conn) bool {
+			io.Copy(ioutil.Discard, conn)
+			return false
+		})
+		defer proxy.Close()
+
+		netConf.DialTimeout = 100 * time.Millisecond
+		netConf.Proxy, _ = egress.NewProxy("", "socks5://"+proxy.addr(), "")
+		writer := egress.NewTCPWriter(
+			buildBinding("syslog://drain.example.com:514"),
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		errs := make(chan error, 1)
+		go func() { errs <- writer.Write(env) }()
+
+		Eventually(errs, 2).Should(Receive(HaveOccurred()))
+	})
+
+	It("tunnels syslog-tls drains through an HTTP proxy", func() {
+		cert, err := tls.X509KeyPair(
+			test_util.MustAsset("adapter.crt"),
+			test_util.MustAsset("adapter.key"),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		tlsDrain := tls.NewListener(drain, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		})
+
+		proxy := newSpyConnectProxy(drain.Addr().String())
+		defer proxy.Close()
+
+		netConf.Proxy, _ = egress.NewProxy("", "http://"+proxy.addr(), "")
+		writer := egress.NewTLSWriter(
+			buildBinding("syslog-tls://drain.example.com:6514"),
+			netConf,
+			true,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			Expect(readMessage(tlsDrain)).To(HaveSuffix("just a test\n"))
+		}()
+
+		Expect(writer.Write(env)).To(Succeed())
+		Expect(proxy.targets()).To(ConsistOf("drain.example.com:6514"))
+	})
+
+	It("connects directly to drains excluded from proxying", func() {
+		proxy := newSpyConnectProxy(drain.Addr().String())
+		defer proxy.Close()
+
+		netConf.Proxy, _ = egress.NewProxy("", "http://"+proxy.addr(), "example.com")
+		writer := egress.NewTCPWriter(
+			buildBinding("syslog://drain.example.com:514"),
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		Expect(writer.Write(env)).ToNot(Succeed())
+		Expect(proxy.targets()).To(BeEmpty())
+	})
+
+	It("connects directly to drains that opt out of proxying", func() {
+		proxy := newSpyConnectProxy(drain.Addr().String())
+		defer proxy.Close()
+
+		netConf.Proxy, _ = egress.NewProxy("", "http://"+proxy.addr(), "")
+		binding := buildBinding("syslog://drain.example.com:514")
+		binding.Options = url.Values{"proxy": {"none"}}
+		writer := egress.NewTCPWriter(
+			binding,
+			netConf,
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		Expect(writer.Write(env)).ToNot(Succeed())
+		Expect(proxy.targets()).To(BeEmpty())
+	})
+
+	Describe("HTTPS drains", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {},
+			))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		var write = func(noProxy string, options url.Values) (*spyConnectProxy, error) {
+			proxy := newSpyConnectProxy(server.Listener.Addr().String())
+
+			netConf.Proxy, _ = egress.NewProxy("http://"+proxy.addr(), "", noProxy)
+			binding := buildBinding("https://drain.example.com/logs")
+			binding.Options = options
+			writer := egress.NewTransportPool(10, nil, nil).NewHTTPSWriter(
+				binding,
+				netConf,
+				true,
+				&testhelper.SpyMetric{},
+			)
+
+			return proxy, writer.Write(env)
+		}
+
+		It("tunnels requests through the HTTPS proxy", func() {
+			proxy, err := write("", nil)
+			defer proxy.Close()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(proxy.targets()).To(ConsistOf("drain.example.com:443"))
+		})
+
+		It("connects directly to drains excluded from proxying", func() {
+			proxy, err := write(".example.com", nil)
+			defer proxy.Close()
+
+			Expect(err).To(HaveOccurred())
+			Expect(proxy.targets()).To(BeEmpty())
+		})
+
+		It("connects directly to drains that opt out of proxying", func() {
+			proxy, err := write("", url.Values{"proxy": {"none"}})
+			defer proxy.Close()
+
+			Expect(err).To(HaveOccurred())
+			Expect(proxy.targets()).To(BeEmpty())
+		})
+	})
+
+	DescribeTable("returns an error for invalid proxies", func(httpsProxy, syslogProxy string) {
+		_, err := egress.NewProxy(httpsProxy, syslogProxy, "")
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("invalid HTTPS proxy", "://nope", ""),
+		Entry("invalid syslog proxy", "", "://nope"),
+		Entry("unsupported syslog proxy scheme", "", "https://proxy:8443"),
+	)
+})
+
+type spyProxy struct {
+	listener net.Listener
+	target   string
+
+	mu       sync.Mutex
+	targets_ []string
+	auth_    []string
+}
+
+func newSpyProxy(target string, handshake func(*spyProxy, net.Conn) bool) *spyProxy {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+
+	return newSpyProxyOn(l, target, handshake)
+}
+
+// newSpyProxyOn returns a proxy that accepts connections on the listener.
+func newSpyProxyOn(l net.Listener, target string, handshake func(*spyProxy, net.Conn) bool) *spyProxy {
+	p := &spyProxy{
+		listener: l,
+		target:   target,
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				if !handshake(p, conn) {
+					return
+				}
+
+				upstream, err := net.Dial("tcp", p.target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return p
+}
+
+func (p *spyProxy) addr() string {
+	return p.listener.Addr().String()
+}
+
+func (p *spyProxy) Close() error {
+	return p.listener.Close()
+}
+
+func (p *spyProxy) record(target, auth string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.targets_ = append(p.targets_, target)
+	if auth != "" {
+		p.auth_ = append(p.auth_, auth)
+	}
+}
+
+func (p *spyProxy) targets() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.targets_
+}
+
+func (p *spyProxy) authorization() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.auth_
+}
+
+type spyConnectProxy struct {
+	*spyProxy
+}
+
+// newSpyConnectProxy returns an HTTP proxy that tunnels every CONNECT
+// request to the target.
+func newSpyConnectProxy(target string) *spyConnectProxy {
+	return &spyConnectProxy{newSpyProxy(target, connectHandshake)}
+}
+
+func connectHandshake(p *spyProxy, conn net.Conn) bool {
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		return false
+	}
+	p.record(req.Host, req.Header.Get("Proxy-Authorization"))
+
+	_, err = fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	return err == nil
+}
+
+// newSpySOCKS5Proxy returns a SOCKS5 proxy without authentication that
+// tunnels every connection to the target.
+func newSpySOCKS5Proxy(target string) *spyProxy {
+	return newSpyProxy(target, socks5Handshake)
+}
+
+func socks5Handshake(p *spyProxy, conn net.Conn) bool {
+	greeting := make([]byte, 2)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return false
+	}
+	if _, err := io.ReadFull(conn, make([]byte, greeting[1])); err != nil {
+		return false
+	}
+	conn.Write([]byte{5, 0})
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return false
+	}
+
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 3:
+		n := make([]byte, 1)
+		io.ReadFull(conn, n)
+		name := make([]byte, n[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	default:
+		return false
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return false
+	}
+	p.record(net.JoinHostPort(host, fmt.Sprint(binary.BigEndian.Uint16(port))), "")
+
+	_, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	return err == nil
+}
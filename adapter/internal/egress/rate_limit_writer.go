@@ -0,0 +1,291 @@
+package egress
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	gendiodes "code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"golang.org/x/net/context"
+)
+
+// rateLimitReportInterval is how often dropped envelopes are reported to the
+// alerter.
+const rateLimitReportInterval = time.Second
+
+// RateLimit caps the number of envelopes and payload bytes per second that
+// are written to a drain, or to all drains of an app. A zero rate means no
+// limit. Burst is how many seconds worth of envelopes or bytes may be
+// written at once.
+type RateLimit struct {
+	Messages float64
+	Bytes    float64
+	Burst    time.Duration
+}
+
+// Enabled reports whether any limit is set.
+func (r RateLimit) Enabled() bool {
+	return r.Messages > 0 || r.Bytes > 0
+}
+
+// forBinding applies the rate-limit, rate-limit-bytes and rate-limit-burst
+// query parameters of a drain URL. Drains may only lower the configured
+// limits. Parameters above the configured limits are ignored and logged.
+func (r RateLimit) forBinding(b *URLBinding) RateLimit {
+	query := b.Options
+
+	if v, err := strconv.ParseFloat(query.Get("rate-limit"), 64); err == nil {
+		r.Messages = lowerLimit(b, "rate-limit", r.Messages, v)
+	}
+
+	if v, err := strconv.ParseFloat(query.Get("rate-limit-bytes"), 64); err == nil {
+		r.Bytes = lowerLimit(b, "rate-limit-bytes", r.Bytes, v)
+	}
+
+	if v, err := time.ParseDuration(query.Get("rate-limit-burst")); err == nil {
+		switch {
+		case r.Burst == 0 || (v > 0 && v < r.Burst):
+			r.Burst = v
+		case v > r.Burst:
+			log.Printf(
+				"ignoring rate-limit-burst %s of syslog drain %s above the adapter limit %s",
+				v, b.drainHost(), r.Burst,
+			)
+		}
+	}
+
+	return r
+}
+
+func lowerLimit(b *URLBinding, param string, current, requested float64) float64 {
+	if requested <= 0 {
+		return current
+	}
+
+	if current == 0 {
+		return requested
+	}
+
+	if requested > current {
+		log.Printf(
+			"ignoring %s %g of syslog drain %s above the adapter limit %g",
+			param, requested, b.drainHost(), current,
+		)
+	}
+
+	return math.Min(current, requested)
+}
+
+// RateLimitWriter drops envelopes that exceed a rate limit before they are
+// handed to the wrapped writer.
+type RateLimitWriter struct {
+	writer  Writer
+	limiter *limiter
+	alerter gendiodes.Alerter
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// NewRateLimitWriter returns a RateLimitWriter that reports the number of
+// dropped envelopes to the alerter once per second until the context is
+// done. Envelopes dropped since the last report are reported when the
+// context is done.
+func NewRateLimitWriter(
+	ctx context.Context,
+	w Writer,
+	limit RateLimit,
+	alerter gendiodes.Alerter,
+) *RateLimitWriter {
+	return newRateLimitWriter(ctx, w, newLimiter(limit), alerter)
+}
+
+func newRateLimitWriter(
+	ctx context.Context,
+	w Writer,
+	l *limiter,
+	alerter gendiodes.Alerter,
+) *RateLimitWriter {
+	r := &RateLimitWriter{
+		writer:  w,
+		limiter: l,
+		alerter: alerter,
+	}
+	go r.reportDropped(ctx)
+
+	return r
+}
+
+// Write writes the envelope to the wrapped writer if it is within the rate
+// limit.
+func (r *RateLimitWriter) Write(env *loggregator_v2.Envelope) error {
+	if !r.limiter.allow(time.Now(), float64(len(env.GetLog().GetPayload()))) {
+		r.mu.Lock()
+		r.dropped++
+		r.mu.Unlock()
+
+		return nil
+	}
+
+	return r.writer.Write(env)
+}
+
+func (r *RateLimitWriter) reportDropped(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-ctx.Done():
+			r.report()
+			return
+		}
+	}
+}
+
+func (r *RateLimitWriter) report() {
+	r.mu.Lock()
+	dropped := r.dropped
+	r.dropped = 0
+	r.mu.Unlock()
+
+	if dropped > 0 {
+		r.alerter.Alert(dropped)
+	}
+}
+
+// limiter caps envelopes and bytes with a token bucket each. It may be
+// shared by the writers of several drains.
+type limiter struct {
+	mu       sync.Mutex
+	messages *tokenBucket
+	bytes    *tokenBucket
+}
+
+func newLimiter(limit RateLimit) *limiter {
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = time.Second
+	}
+
+	return &limiter{
+		messages: newTokenBucket(limit.Messages, burst),
+		bytes:    newTokenBucket(limit.Bytes, burst),
+	}
+}
+
+// allow takes a token for the envelope and its size in bytes. It returns
+// false and takes nothing if either bucket is exhausted.
+func (l *limiter) allow(now time.Time, size float64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.messages.available(now, 1) || !l.bytes.available(now, size) {
+		return false
+	}
+
+	l.messages.take(1)
+	l.bytes.take(size)
+
+	return true
+}
+
+// appLimiters shares a limiter between the drains of each app, so that an
+// app with several drains is held to the same limit as an app with one.
+type appLimiters struct {
+	limit RateLimit
+
+	mu       sync.Mutex
+	limiters map[string]*appLimiter
+}
+
+type appLimiter struct {
+	limiter *limiter
+	refs    int
+}
+
+func newAppLimiters(limit RateLimit) *appLimiters {
+	return &appLimiters{
+		limit:    limit,
+		limiters: make(map[string]*appLimiter),
+	}
+}
+
+// acquire returns the limiter of the app and adds a reference to it. Each
+// call must be matched by a call to release.
+func (a *appLimiters) acquire(appID string) *limiter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	al, ok := a.limiters[appID]
+	if !ok {
+		al = &appLimiter{limiter: newLimiter(a.limit)}
+		a.limiters[appID] = al
+	}
+	al.refs++
+
+	return al.limiter
+}
+
+// release removes a reference to the limiter of the app. The limiter is
+// removed once the last drain of the app is gone.
+func (a *appLimiters) release(appID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	al, ok := a.limiters[appID]
+	if !ok {
+		return
+	}
+
+	al.refs--
+	if al.refs <= 0 {
+		delete(a.limiters, appID)
+	}
+}
+
+// tokenBucket is a token bucket that refills at a constant rate up to its
+// capacity. A bucket with a zero rate never runs out of tokens.
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, burst time.Duration) *tokenBucket {
+	capacity := rate * burst.Seconds()
+
+	return &tokenBucket{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// available refills the bucket and reports whether n tokens can be taken.
+// Requests larger than the capacity are allowed once the bucket is full.
+func (b *tokenBucket) available(now time.Time, n float64) bool {
+	if b.rate <= 0 {
+		return true
+	}
+
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	return b.tokens >= math.Min(n, b.capacity)
+}
+
+func (b *tokenBucket) take(n float64) {
+	if b.rate <= 0 {
+		return
+	}
+
+	b.tokens -= n
+}
@@ -0,0 +1,241 @@
+package egress_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimitWriter", func() {
+	var (
+		spyWriter  *SpyWriter
+		spyAlerter *SpyAlerter
+		env        *loggregator_v2.Envelope
+		ctx        context.Context
+		cancel     func()
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		spyWriter = &SpyWriter{}
+		spyAlerter = &SpyAlerter{}
+		env = buildLogEnvelope("APP", "1", "0123456789", loggregator_v2.Log_OUT)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("writes envelopes within the limit", func() {
+		w := egress.NewRateLimitWriter(ctx, spyWriter, egress.RateLimit{
+			Messages: 10,
+		}, spyAlerter)
+
+		for i := 0; i < 10; i++ {
+			Expect(w.Write(env)).To(Succeed())
+		}
+
+		Expect(spyWriter.calledWith()).To(HaveLen(10))
+	})
+
+	It("drops envelopes over the message rate", func() {
+		w := egress.NewRateLimitWriter(ctx, spyWriter, egress.RateLimit{
+			Messages: 5,
+		}, spyAlerter)
+
+		for i := 0; i < 20; i++ {
+			Expect(w.Write(env)).To(Succeed())
+		}
+
+		Expect(spyWriter.calledWith()).To(HaveLen(5))
+	})
+
+	It("drops envelopes over the byte rate", func() {
+		w := egress.NewRateLimitWriter(ctx, spyWriter, egress.RateLimit{
+			Bytes: 30,
+		}, spyAlerter)
+
+		for i := 0; i < 20; i++ {
+			Expect(w.Write(env)).To(Succeed())
+		}
+
+		Expect(spyWriter.calledWith()).To(HaveLen(3))
+	})
+
+	It("allows a burst of several seconds worth of envelopes", func() {
+		w := egress.NewRateLimitWriter(ctx, spyWriter, egress.RateLimit{
+			Messages: 5,
+			Burst:    2 * time.Second,
+		}, spyAlerter)
+
+		for i := 0; i < 20; i++ {
+			Expect(w.Write(env)).To(Succeed())
+		}
+
+		Expect(spyWriter.calledWith()).To(HaveLen(10))
+	})
+
+	It("refills over time", func() {
+		w := egress.NewRateLimitWriter(ctx, spyWriter, egress.RateLimit{
+			Messages: 100,
+		}, spyAlerter)
+
+		for i := 0; i < 200; i++ {
+			Expect(w.Write(env)).To(Succeed())
+		}
+		Expect(spyWriter.calledWith()).To(HaveLen(100))
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(w.Write(env)).To(Succeed())
+		Expect(spyWriter.calledWith()).To(HaveLen(101))
+	})
+
+	It("reports dropped envelopes to the alerter", func() {
+		w := egress.NewRateLimitWriter(ctx, spyWriter, egress.RateLimit{
+			Messages: 1,
+		}, spyAlerter)
+
+		for i := 0; i < 10; i++ {
+			w.Write(env)
+		}
+		Expect(spyAlerter.missed()).To(BeZero())
+
+		Eventually(spyAlerter.missed, 3).Should(BeNumerically("==", 9))
+	})
+
+	It("reports the remaining dropped envelopes when the context is done", func() {
+		w := egress.NewRateLimitWriter(ctx, spyWriter, egress.RateLimit{
+			Messages: 1,
+		}, spyAlerter)
+
+		for i := 0; i < 10; i++ {
+			w.Write(env)
+		}
+		cancel()
+
+		Eventually(spyAlerter.missed, 0.5).Should(BeNumerically("==", 9))
+	})
+})
+
+var _ = Describe("SyslogConnector rate limiting", func() {
+	var constructor = func(
+		*egress.URLBinding,
+		egress.NetworkTimeoutConfig,
+		bool,
+		pulseemitter.CounterMetric,
+	) egress.WriteCloser {
+		return &SleepWriterCloser{metric: nullMetric{}}
+	}
+
+	It("limits drains with a rate-limit parameter", func() {
+		droppedMetric := &testhelper.SpyMetric{}
+		logClient := newSpyLogClient()
+		connector := egress.NewSyslogConnector(
+			egress.NetworkTimeoutConfig{},
+			true,
+			&SpyWaitGroup{},
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"syslog": constructor,
+			}),
+			egress.WithDroppedMetrics(map[string]pulseemitter.CounterMetric{
+				"syslog": droppedMetric,
+			}),
+			egress.WithLogClient(logClient, "3"),
+		)
+
+		writer, err := connector.Connect(context.Background(), &v1.Binding{
+			AppId: "app-id",
+			Drain: "syslog://some-host:514?rate-limit=1",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() uint64 {
+			writer.Write(&loggregator_v2.Envelope{SourceId: "app-id"})
+			return droppedMetric.Delta()
+		}, 3).Should(BeNumerically(">", 0))
+
+		Expect(logClient.message()).To(ContainElement(
+			MatchRegexp("\\d+ messages dropped by syslog drain rate limit"),
+		))
+		Expect(logClient.sourceType()).To(HaveKey("LGR"))
+	})
+
+	It("does not let drains raise the adapter rate limit", func() {
+		droppedMetric := &testhelper.SpyMetric{}
+		connector := egress.NewSyslogConnector(
+			egress.NetworkTimeoutConfig{},
+			true,
+			&SpyWaitGroup{},
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"syslog": constructor,
+			}),
+			egress.WithDroppedMetrics(map[string]pulseemitter.CounterMetric{
+				"syslog": droppedMetric,
+			}),
+			egress.WithRateLimit(egress.RateLimit{Messages: 1}),
+		)
+
+		writer, err := connector.Connect(context.Background(), &v1.Binding{
+			AppId: "app-id",
+			Drain: "syslog://some-host:514?rate-limit=1000000",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() uint64 {
+			writer.Write(&loggregator_v2.Envelope{SourceId: "app-id"})
+			return droppedMetric.Delta()
+		}, 3).Should(BeNumerically(">", 0))
+	})
+
+	It("shares the app rate limit between the drains of an app", func() {
+		appLimitMetric := &testhelper.SpyMetric{}
+		logClient := newSpyLogClient()
+		connector := egress.NewSyslogConnector(
+			egress.NetworkTimeoutConfig{},
+			true,
+			&SpyWaitGroup{},
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"syslog": constructor,
+			}),
+			egress.WithAppRateLimit(egress.RateLimit{Messages: 10}, appLimitMetric),
+			egress.WithLogClient(logClient, "3"),
+		)
+
+		var writers []egress.Writer
+		for _, drain := range []string{"syslog://host-1:514", "syslog://host-2:514"} {
+			writer, err := connector.Connect(context.Background(), &v1.Binding{
+				AppId: "app-id",
+				Drain: drain,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			writers = append(writers, writer)
+		}
+
+		other, err := connector.Connect(context.Background(), &v1.Binding{
+			AppId: "other-app-id",
+			Drain: "syslog://host-1:514",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 10; i++ {
+			for _, w := range writers {
+				w.Write(&loggregator_v2.Envelope{SourceId: "app-id"})
+			}
+			other.Write(&loggregator_v2.Envelope{SourceId: "other-app-id"})
+		}
+
+		Eventually(appLimitMetric.Delta, 3).Should(BeNumerically(">=", 9))
+		Consistently(appLimitMetric.Delta).Should(BeNumerically("<=", 10))
+		Expect(logClient.message()).To(ContainElement(
+			MatchRegexp("\\d+ messages dropped by app rate limit"),
+		))
+	})
+})
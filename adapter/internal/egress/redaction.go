@@ -0,0 +1,190 @@
+package egress
+
+import (
+	"regexp"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+const redacted = "[REDACTED]"
+
+// RedactionPattern is a pattern that is masked in log payloads before they
+// are written to a drain. Replacement may refer to submatches of the pattern
+// as described in regexp.Regexp.Expand.
+type RedactionPattern struct {
+	Name        string
+	Regexp      *regexp.Regexp
+	Replacement string
+
+	// validate is an additional check a match has to pass to be redacted.
+	validate func([]byte) bool
+}
+
+// NewRedactionPattern returns a pattern that replaces all matches of the
+// expression.
+func NewRedactionPattern(name, expr string) (RedactionPattern, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return RedactionPattern{}, err
+	}
+
+	return RedactionPattern{
+		Name:        name,
+		Regexp:      re,
+		Replacement: redacted,
+	}, nil
+}
+
+// BuiltinRedactionPatterns returns patterns for credit card numbers, bearer
+// tokens and AWS credentials.
+func BuiltinRedactionPatterns() []RedactionPattern {
+	return []RedactionPattern{
+		{
+			Name:        "credit_card",
+			Regexp:      regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+			Replacement: redacted,
+			validate:    luhn,
+		},
+		{
+			Name:        "bearer_token",
+			Regexp:      regexp.MustCompile(`(?i)(\bbearer\s+)[a-z0-9\-._~+/]+=*`),
+			Replacement: "${1}" + redacted,
+		},
+		{
+			Name:        "aws_access_key",
+			Regexp:      regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+			Replacement: redacted,
+		},
+		{
+			Name:        "aws_secret_key",
+			Regexp:      regexp.MustCompile(`(?i)(aws_secret_access_key["']?\s*[=:]\s*["']?)[a-z0-9/+=]{40}`),
+			Replacement: "${1}" + redacted,
+		},
+	}
+}
+
+func (p RedactionPattern) redact(payload []byte) ([]byte, int) {
+	matches := p.Regexp.FindAllSubmatchIndex(payload, -1)
+
+	var (
+		res   []byte
+		last  int
+		count int
+	)
+	for _, m := range matches {
+		if p.validate != nil && !p.validate(payload[m[0]:m[1]]) {
+			continue
+		}
+
+		res = append(res, payload[last:m[0]]...)
+		res = p.Regexp.Expand(res, []byte(p.Replacement), payload, m)
+		last = m[1]
+		count++
+	}
+
+	if count == 0 {
+		return payload, 0
+	}
+
+	return append(res, payload[last:]...), count
+}
+
+// luhn reports whether the digits in b pass the Luhn checksum used by credit
+// card numbers.
+func luhn(b []byte) bool {
+	var sum, digits int
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '0' || b[i] > '9' {
+			continue
+		}
+
+		d := int(b[i] - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		digits++
+	}
+
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// Redactor masks patterns in log payloads and counts the matches of each
+// pattern.
+type Redactor struct {
+	patterns []RedactionPattern
+	metrics  map[string]pulseemitter.CounterMetric
+}
+
+// NewRedactor returns a Redactor for the given patterns. The metrics are
+// keyed by pattern name.
+func NewRedactor(
+	patterns []RedactionPattern,
+	metrics map[string]pulseemitter.CounterMetric,
+) *Redactor {
+	return &Redactor{
+		patterns: patterns,
+		metrics:  metrics,
+	}
+}
+
+// Redact returns the envelope with all patterns masked in its log payload.
+// The envelope is copied if anything is masked so envelopes shared with
+// other bindings are left untouched.
+func (r *Redactor) Redact(env *loggregator_v2.Envelope) *loggregator_v2.Envelope {
+	log := env.GetLog()
+	if log == nil {
+		return env
+	}
+
+	payload := log.Payload
+	var changed bool
+	for _, p := range r.patterns {
+		var count int
+		payload, count = p.redact(payload)
+		if count == 0 {
+			continue
+		}
+
+		changed = true
+		if m, ok := r.metrics[p.Name]; ok {
+			m.Increment(uint64(count))
+		}
+	}
+
+	if !changed {
+		return env
+	}
+
+	redactedLog := *log
+	redactedLog.Payload = payload
+	redactedEnv := *env
+	redactedEnv.Message = &loggregator_v2.Envelope_Log{Log: &redactedLog}
+
+	return &redactedEnv
+}
+
+// RedactingWriter redacts envelopes before they are formatted and written by
+// the wrapped writer.
+type RedactingWriter struct {
+	WriteCloser
+	redactor *Redactor
+}
+
+// NewRedactingWriter returns a RedactingWriter.
+func NewRedactingWriter(w WriteCloser, r *Redactor) *RedactingWriter {
+	return &RedactingWriter{
+		WriteCloser: w,
+		redactor:    r,
+	}
+}
+
+// Write redacts the envelope and writes it to the wrapped writer.
+func (w *RedactingWriter) Write(env *loggregator_v2.Envelope) error {
+	return w.WriteCloser.Write(w.redactor.Redact(env))
+}
@@ -1,10 +1,12 @@
 package egress
 
 import (
+	"fmt"
 	"log"
 	"math"
 	"time"
 
+	loggregator "code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
 )
@@ -52,6 +54,10 @@ type RetryWriter struct {
 	binding       *URLBinding
 	logClient     LogClient
 	sourceIndex   string
+
+	// handshakeFailure is the last TLS handshake failure reported to the
+	// app. It is reset once a write succeeds.
+	handshakeFailure string
 }
 
 // Write will retry writes unitl maxRetries has been reached.
@@ -63,9 +69,12 @@ func (r *RetryWriter) Write(e *loggregator_v2.Envelope) error {
 	for i := 0; i < r.maxRetries; i++ {
 		err = r.writer.Write(e)
 		if err == nil {
+			r.handshakeFailure = ""
 			return nil
 		}
 
+		r.reportHandshakeFailure(err)
+
 		if contextDone(r.binding.Context) {
 			return err
 		}
@@ -79,6 +88,25 @@ func (r *RetryWriter) Write(e *loggregator_v2.Envelope) error {
 	return err
 }
 
+// reportHandshakeFailure emits an app log when the error is a TLS handshake
+// failure. The same failure is only reported once in a row.
+func (r *RetryWriter) reportHandshakeFailure(err error) {
+	reason, ok := handshakeFailure(err)
+	if !ok || reason == r.handshakeFailure {
+		return
+	}
+	r.handshakeFailure = reason
+
+	msg := fmt.Sprintf(
+		"TLS handshake with syslog drain %s failed: %s",
+		r.binding.URL.Host,
+		reason,
+	)
+
+	r.logClient.EmitLog(msg, loggregator.WithAppInfo(r.binding.AppID, "LGR", ""))
+	r.logClient.EmitLog(msg, loggregator.WithAppInfo(r.binding.AppID, "SYS", r.sourceIndex))
+}
+
 // Close delegates to the syslog writer.
 func (r *RetryWriter) Close() error {
 	return r.writer.Close()
@@ -1,6 +1,7 @@
 package egress_test
 
 import (
+	"crypto/x509"
 	"errors"
 	"net/url"
 	"sync"
@@ -106,6 +107,45 @@ var _ = Describe("Retry Writer", func() {
 		})
 	})
 
+	Describe("TLS handshake failures", func() {
+		var buildWriteCloser = func(err error) *spyWriteCloser {
+			return &spyWriteCloser{
+				returnErrCount: 3,
+				writeErr:       err,
+				binding: &egress.URLBinding{
+					AppID:   "some-app-id",
+					URL:     &url.URL{Host: "some-host:514"},
+					Context: context.Background(),
+				},
+			}
+		}
+
+		It("reports the failure to the app once", func() {
+			logClient := newSpyLogClient()
+			r := buildRetryWriter(buildWriteCloser(x509.UnknownAuthorityError{}), 3, 0, logClient, "3")
+
+			Expect(r.Write(&v2.Envelope{})).ToNot(Succeed())
+
+			Expect(logClient.message()).To(ConsistOf(
+				"TLS handshake with syslog drain some-host:514 failed: certificate signed by unknown authority",
+				"TLS handshake with syslog drain some-host:514 failed: certificate signed by unknown authority",
+			))
+			Expect(logClient.appID()).To(ConsistOf("some-app-id", "some-app-id"))
+			Expect(logClient.sourceType()).To(HaveKey("LGR"))
+			Expect(logClient.sourceType()).To(HaveKey("SYS"))
+			Expect(logClient.sourceInstance()).To(HaveKey("3"))
+		})
+
+		It("does not report other failures", func() {
+			logClient := newSpyLogClient()
+			r := buildRetryWriter(buildWriteCloser(errors.New("write error")), 3, 0, logClient, "3")
+
+			Expect(r.Write(&v2.Envelope{})).ToNot(Succeed())
+
+			Expect(logClient.message()).To(BeEmpty())
+		})
+	})
+
 	Describe("ExponentialDuration", func() {
 		var backoffTests = []struct {
 			attempt  int
@@ -0,0 +1,124 @@
+package egress
+
+import (
+	"hash/fnv"
+	"log"
+	"math"
+	"strconv"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/rfc5424"
+)
+
+const sampleStructuredDataID = "sample@47450"
+
+// Sampling modes for drains with a sample rate. They are selected with the
+// sample-mode query parameter of the drain URL.
+const (
+	sampleAll    = "all"
+	sampleStdout = "stdout"
+)
+
+// sampler decides which log envelopes of a binding are written when the
+// drain has a sample-rate query parameter. The rate is the fraction of logs
+// that is kept, e.g. 0.1 keeps one in ten. With the stdout sample mode all
+// stderr logs are kept and only stdout logs are sampled.
+//
+// Sampling is deterministic: whether a log is kept depends only on its
+// instance and timestamp. Kept logs carry the rate in their structured data
+// so receivers can extrapolate.
+type sampler struct {
+	rate float64
+	mode string
+}
+
+func newSampler(b *URLBinding) *sampler {
+	s := &sampler{rate: 1, mode: sampleAll}
+
+	query := b.Options
+	raw := query.Get("sample-rate")
+	if raw == "" {
+		return s
+	}
+
+	rate, err := strconv.ParseFloat(raw, 64)
+	if err != nil || rate <= 0 || rate > 1 {
+		log.Printf("invalid sample rate %q for syslog drain %s", raw, b.drainHost())
+		return s
+	}
+	s.rate = rate
+
+	switch mode := query.Get("sample-mode"); mode {
+	case "", sampleAll:
+	case sampleStdout:
+		s.mode = sampleStdout
+	default:
+		log.Printf("unknown sample mode %q for syslog drain %s", mode, b.drainHost())
+	}
+
+	return s
+}
+
+func (s *sampler) enabled() bool {
+	return s.rate < 1
+}
+
+// sampled reports whether the envelope is subject to sampling.
+func (s *sampler) sampled(env *loggregator_v2.Envelope) bool {
+	l := env.GetLog()
+	if s.rate >= 1 || l == nil {
+		return false
+	}
+
+	return s.mode != sampleStdout || l.Type == loggregator_v2.Log_OUT
+}
+
+// keep reports whether the envelope should be written.
+func (s *sampler) keep(env *loggregator_v2.Envelope) bool {
+	if !s.sampled(env) {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(env.GetInstanceId()))
+	h.Write([]byte(strconv.FormatInt(env.GetTimestamp(), 10)))
+
+	return float64(h.Sum32()) < s.rate*(math.MaxUint32+1)
+}
+
+// annotate adds the sample rate to the structured data of messages formatted
+// from a sampled envelope.
+func (s *sampler) annotate(env *loggregator_v2.Envelope, msgs []rfc5424.Message) {
+	if !s.sampled(env) {
+		return
+	}
+
+	for i := range msgs {
+		msgs[i].StructuredData = append(msgs[i].StructuredData, rfc5424.StructuredData{
+			ID: sampleStructuredDataID,
+			Parameters: []rfc5424.SDParam{
+				{
+					Name:  "rate",
+					Value: strconv.FormatFloat(s.rate, 'g', -1, 64),
+				},
+			},
+		})
+	}
+}
+
+// samplingWriter drops the envelopes the sampler does not keep and
+// annotates the messages of the sampled envelopes it keeps.
+type samplingWriter struct {
+	next    messageWriter
+	sampler *sampler
+}
+
+func (w *samplingWriter) writeMessages(env *loggregator_v2.Envelope, msgs []rfc5424.Message) error {
+	if !w.sampler.keep(env) {
+		return nil
+	}
+
+	w.sampler.annotate(env, msgs)
+
+	return w.next.writeMessages(env, msgs)
+}
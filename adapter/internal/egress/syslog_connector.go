@@ -1,6 +1,7 @@
 package egress
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -45,12 +46,24 @@ type SyslogConnector struct {
 	keepalive      time.Duration
 	ioTimeout      time.Duration
 	dialTimeout    time.Duration
+	failback       time.Duration
+	bufferSize     int
+	linger         time.Duration
+	clientCert     *tls.Certificate
+	tlsPolicy      *TLSPolicy
+	proxy          *Proxy
+	messageIDs     bool
 	constructors   map[string]WriterConstructor
 	droppedMetrics map[string]pulseemitter.CounterMetric
 	egressMetrics  map[string]pulseemitter.CounterMetric
 	logClient      LogClient
 	wg             WaitGroup
 	sourceIndex    string
+	rateLimit      RateLimit
+	appLimiters    *appLimiters
+	appLimitMetric pulseemitter.CounterMetric
+	redactor       *Redactor
+	loadMeter      *LoadMeter
 }
 
 // NewSyslogConnector configures and returns a new SyslogConnector.
@@ -64,6 +77,13 @@ func NewSyslogConnector(
 		keepalive:      netConf.Keepalive,
 		ioTimeout:      netConf.WriteTimeout,
 		dialTimeout:    netConf.DialTimeout,
+		failback:       netConf.FailbackInterval,
+		bufferSize:     netConf.WriteBufferSize,
+		linger:         netConf.WriteLinger,
+		clientCert:     netConf.ClientCert,
+		tlsPolicy:      netConf.TLSPolicy,
+		proxy:          netConf.Proxy,
+		messageIDs:     netConf.MessageIDs,
 		skipCertVerify: skipCertVerify,
 		wg:             wg,
 		logClient:      nullLogClient{},
@@ -123,6 +143,43 @@ func WithLogClient(logClient LogClient, sourceIndex string) ConnectorOption {
 	}
 }
 
+// WithRateLimit sets the rate limit of each drain. Drains may lower the
+// limit with the rate-limit, rate-limit-bytes and rate-limit-burst query
+// parameters.
+func WithRateLimit(limit RateLimit) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.rateLimit = limit
+	}
+}
+
+// WithAppRateLimit sets a rate limit that the drains of each app share.
+// Envelopes dropped by the limit are counted by the metric, which may be
+// nil, and by the dropped metric of the drain.
+func WithAppRateLimit(limit RateLimit, metric pulseemitter.CounterMetric) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		if limit.Enabled() {
+			sc.appLimiters = newAppLimiters(limit)
+		}
+		sc.appLimitMetric = metric
+	}
+}
+
+// WithRedactor masks sensitive data in the log payloads of every binding
+// before they are written.
+func WithRedactor(r *Redactor) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.redactor = r
+	}
+}
+
+// WithLoadMeter counts the envelopes written to each binding and reports
+// the fill of its buffer to the load meter.
+func WithLoadMeter(m *LoadMeter) ConnectorOption {
+	return func(sc *SyslogConnector) {
+		sc.loadMeter = m
+	}
+}
+
 // Connect returns an egress writer based on the scheme of the binding drain
 // URL.
 func (w *SyslogConnector) Connect(ctx context.Context, b *v1.Binding) (Writer, error) {
@@ -142,16 +199,44 @@ func (w *SyslogConnector) Connect(ctx context.Context, b *v1.Binding) (Writer, e
 		return nil, errors.New("unsupported protocol")
 	}
 	netConf := NetworkTimeoutConfig{
-		Keepalive:    w.keepalive,
-		DialTimeout:  w.dialTimeout,
-		WriteTimeout: w.ioTimeout,
-	}
-	writer := constructor(
-		urlBinding,
-		netConf,
-		w.skipCertVerify,
-		egressMetric,
-	)
+		Keepalive:        w.keepalive,
+		DialTimeout:      w.dialTimeout,
+		WriteTimeout:     w.ioTimeout,
+		FailbackInterval: w.failback,
+		WriteBufferSize:  w.bufferSize,
+		WriteLinger:      w.linger,
+		ClientCert:       w.clientCert,
+		TLSPolicy:        w.tlsPolicy,
+		Proxy:            w.proxy,
+		MessageIDs:       w.messageIDs,
+	}
+	newWriter := func() WriteCloser {
+		writer := constructor(
+			urlBinding,
+			netConf,
+			w.skipCertVerify,
+			egressMetric,
+		)
+		if w.redactor != nil {
+			writer = NewRedactingWriter(writer, w.redactor)
+		}
+
+		return writer
+	}
+
+	writer := newWriter()
+	if urlBinding.Scheme() == "https" {
+		if n, ordered := inFlight(urlBinding); n > 1 {
+			writers := []WriteCloser{writer}
+			for i := 1; i < n; i++ {
+				writers = append(writers, newWriter())
+			}
+			writer = NewParallelWriter(ctx, writers, ordered)
+		}
+	}
+
+	counter := w.loadMeter.Counter(b)
+	writer = counter.wrap(writer)
 
 	anonymousUrl := *urlBinding.URL
 	anonymousUrl.User = nil
@@ -168,8 +253,50 @@ func (w *SyslogConnector) Connect(ctx context.Context, b *v1.Binding) (Writer, e
 			missed, urlBinding.Scheme(), anonymousUrl.String(), b.AppId,
 		)
 	}), w.wg)
+	counter.setBuffer(dw)
+
+	var limited Writer = dw
+	if w.appLimiters != nil {
+		l := w.appLimiters.acquire(b.AppId)
+		go func() {
+			<-ctx.Done()
+			w.appLimiters.release(b.AppId)
+		}()
+
+		limited = newRateLimitWriter(ctx, limited, l, diodes.AlertFunc(func(missed int) {
+			if droppedMetric != nil {
+				droppedMetric.Increment(uint64(missed))
+			}
+			if w.appLimitMetric != nil {
+				w.appLimitMetric.Increment(uint64(missed))
+			}
+
+			w.emitErrorLog(b.AppId, fmt.Sprintf("%d messages dropped by app rate limit", missed))
+
+			log.Printf(
+				"App rate limited %d %s logs for url %s in app %s",
+				missed, urlBinding.Scheme(), anonymousUrl.String(), b.AppId,
+			)
+		}))
+	}
+
+	limit := w.rateLimit.forBinding(urlBinding)
+	if limit.Enabled() {
+		limited = NewRateLimitWriter(ctx, limited, limit, diodes.AlertFunc(func(missed int) {
+			if droppedMetric != nil {
+				droppedMetric.Increment(uint64(missed))
+			}
+
+			w.emitErrorLog(b.AppId, fmt.Sprintf("%d messages dropped by syslog drain rate limit", missed))
+
+			log.Printf(
+				"Rate limited %d %s logs for url %s in app %s",
+				missed, urlBinding.Scheme(), anonymousUrl.String(), b.AppId,
+			)
+		}))
+	}
 
-	return dw, nil
+	return limited, nil
 }
 
 func (w *SyslogConnector) emitErrorLog(appID, message string) {
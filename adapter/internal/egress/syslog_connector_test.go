@@ -2,6 +2,7 @@ package egress_test
 
 import (
 	"io"
+	"net/url"
 	"time"
 
 	"golang.org/x/net/context"
@@ -13,6 +14,7 @@ import (
 	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -104,6 +106,216 @@ var _ = Describe("SyslogConnector", func() {
 		Expect(err).To(MatchError("unsupported protocol"))
 	})
 
+	It("passes fallback drains to the constructor", func() {
+		var binding *egress.URLBinding
+		constructor := func(
+			b *egress.URLBinding,
+			_ egress.NetworkTimeoutConfig,
+			_ bool,
+			_ pulseemitter.CounterMetric,
+		) egress.WriteCloser {
+			binding = b
+			return &SleepWriterCloser{metric: nullMetric{}}
+		}
+
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"syslog": constructor,
+			}),
+		)
+
+		_, err := connector.Connect(ctx, &v1.Binding{
+			Drain: "syslog://primary:514?drain-type=logs&fallback=syslog://secondary:514&fallback=syslog://tertiary:514",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(binding.URL.String()).To(Equal("syslog://primary:514?drain-type=logs"))
+		Expect(binding.Fallbacks).To(HaveLen(2))
+		Expect(binding.Fallbacks[0].Host).To(Equal("secondary:514"))
+		Expect(binding.Fallbacks[1].Host).To(Equal("tertiary:514"))
+	})
+
+	It("removes the adapter options from the drain URL", func() {
+		var binding *egress.URLBinding
+		constructor := func(
+			b *egress.URLBinding,
+			_ egress.NetworkTimeoutConfig,
+			_ bool,
+			_ pulseemitter.CounterMetric,
+		) egress.WriteCloser {
+			binding = b
+			return &SleepWriterCloser{metric: nullMetric{}}
+		}
+
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"https": constructor,
+			}),
+		)
+
+		_, err := connector.Connect(ctx, &v1.Binding{
+			Drain: "https://some-host/logs?drain-type=logs&token=abc" +
+				"&rate-limit=10&rate-limit-bytes=100&rate-limit-burst=2s" +
+				"&include=err.*&exclude=debug&source-type=APP&exclude-source-type=RTR" +
+				"&sample-rate=0.5&sample-mode=stdout" +
+				"&max-message-size=100&max-message-mode=split" +
+				"&in-flight=4&ordering=instance&replicas=3&balance=round-robin",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(binding.URL.String()).To(Equal("https://some-host/logs?drain-type=logs&token=abc"))
+		Expect(binding.Options).To(Equal(url.Values{
+			"rate-limit":          {"10"},
+			"rate-limit-bytes":    {"100"},
+			"rate-limit-burst":    {"2s"},
+			"include":             {"err.*"},
+			"exclude":             {"debug"},
+			"source-type":         {"APP"},
+			"exclude-source-type": {"RTR"},
+			"sample-rate":         {"0.5"},
+			"sample-mode":         {"stdout"},
+			"max-message-size":    {"100"},
+			"max-message-mode":    {"split"},
+			"in-flight":           {"4"},
+			"ordering":            {"instance"},
+			"replicas":            {"3"},
+			"balance":             {"round-robin"},
+		}))
+	})
+
+	It("passes drain headers and the signing secret to the constructor", func() {
+		var binding *egress.URLBinding
+		constructor := func(
+			b *egress.URLBinding,
+			_ egress.NetworkTimeoutConfig,
+			_ bool,
+			_ pulseemitter.CounterMetric,
+		) egress.WriteCloser {
+			binding = b
+			return &SleepWriterCloser{metric: nullMetric{}}
+		}
+
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"https": constructor,
+			}),
+		)
+
+		_, err := connector.Connect(ctx, &v1.Binding{
+			Drain: "https://some-host/logs?drain-type=logs&header=X-Api-Key%3A+some-key&header=X-Tenant%3Aa&header=X-Tenant%3Ab&bearer-token=some-token&hmac-secret=some-secret",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(binding.URL.String()).To(Equal("https://some-host/logs?drain-type=logs"))
+		Expect(binding.Headers.Get("X-Api-Key")).To(Equal("some-key"))
+		Expect(binding.Headers["X-Tenant"]).To(Equal([]string{"a", "b"}))
+		Expect(binding.Headers.Get("Authorization")).To(Equal("Bearer some-token"))
+		Expect(binding.SigningSecret).To(Equal([]byte("some-secret")))
+	})
+
+	It("returns an error for an invalid client certificate", func() {
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+		)
+
+		_, err := connector.Connect(ctx, &v1.Binding{
+			Drain:      "syslog-tls://some-host:514",
+			ClientCert: "invalid",
+			ClientKey:  "invalid",
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("passes the drain trust and server name to the constructor", func() {
+		var binding *egress.URLBinding
+		constructor := func(
+			b *egress.URLBinding,
+			_ egress.NetworkTimeoutConfig,
+			_ bool,
+			_ pulseemitter.CounterMetric,
+		) egress.WriteCloser {
+			binding = b
+			return &SleepWriterCloser{metric: nullMetric{}}
+		}
+
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+			egress.WithConstructors(map[string]egress.WriterConstructor{
+				"syslog-tls": constructor,
+			}),
+		)
+
+		_, err := connector.Connect(ctx, &v1.Binding{
+			Drain:    "syslog-tls://some-host:514?sni=drain.example.com",
+			SpkiPins: "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=, 47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(binding.Trust).ToNot(BeNil())
+		Expect(binding.ServerName).To(Equal("drain.example.com"))
+		Expect(binding.URL.String()).To(Equal("syslog-tls://some-host:514"))
+	})
+
+	It("returns an error for an invalid SPKI pin", func() {
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+		)
+
+		_, err := connector.Connect(ctx, &v1.Binding{
+			Drain:    "syslog-tls://some-host:514",
+			SpkiPins: "invalid",
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("returns an error without the value for an invalid drain header", func(header string) {
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+		)
+
+		_, err := connector.Connect(ctx, &v1.Binding{
+			Drain: "https://some-host?header=" + url.QueryEscape(header),
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).ToNot(ContainSubstring("secret"))
+	},
+		Entry("without a separator", "X-Api-Key secret"),
+		Entry("with an invalid name", "X Api Key: secret"),
+		Entry("with a reserved name", "Host: secret"),
+		Entry("with a line break in the value", "X-Api-Key: secret\r\nX-Other: secret"),
+	)
+
+	It("returns an error for a fallback drain with a different scheme", func() {
+		connector := egress.NewSyslogConnector(
+			netConf,
+			true,
+			spyWaitGroup,
+		)
+
+		binding := &v1.Binding{
+			Drain: "syslog://primary:514?fallback=https://secondary",
+		}
+
+		_, err := connector.Connect(ctx, binding)
+		Expect(err).To(HaveOccurred())
+	})
+
 	It("returns an error for an inproperly formatted drain", func() {
 		connector := egress.NewSyslogConnector(
 			netConf,
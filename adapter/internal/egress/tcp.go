@@ -24,19 +24,27 @@ const (
 )
 
 // DialFunc represents a method for creating a connection, either TCP or TLS.
-type DialFunc func(addr string) (net.Conn, error)
+// The server name is the host name of the drain the address belongs to.
+type DialFunc func(addr, serverName string) (net.Conn, error)
 
 // TCPWriter represents a syslog writer that connects over unencrypted TCP.
 // This writer is not meant to be used from multiple goroutines. The same
 // goroutine that calls `.Write()` should be the one that calls `.Close()`.
 type TCPWriter struct {
-	url          *url.URL
+	endpoints    *endpoints
 	appID        string
 	hostname     string
 	dialFunc     DialFunc
 	writeTimeout time.Duration
+	bufferSize   int
+	linger       time.Duration
 	scheme       string
-	conn         net.Conn
+	pool         connPool
+	messages     messageWriter
+
+	balance        string
+	resolver       Resolver
+	resolveTimeout time.Duration
 
 	egressMetric pulseemitter.CounterMetric
 }
@@ -52,52 +60,145 @@ func NewTCPWriter(
 		Timeout:   netConf.DialTimeout,
 		KeepAlive: netConf.Keepalive,
 	}
-	df := func(addr string) (net.Conn, error) {
-		return dialer.Dial("tcp", addr)
+	proxy := netConf.Proxy.forDrain(binding)
+	df := func(addr, serverName string) (net.Conn, error) {
+		return proxy.dial(dialer, addr, serverName)
+	}
+
+	return newTCPWriter(binding, netConf, df, "syslog", egressMetric)
+}
+
+func newTCPWriter(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	df DialFunc,
+	scheme string,
+	egressMetric pulseemitter.CounterMetric,
+) *TCPWriter {
+	var r Resolver = net.DefaultResolver
+	if netConf.Resolver != nil {
+		r = netConf.Resolver
 	}
 
 	w := &TCPWriter{
-		url:          binding.URL,
-		appID:        binding.AppID,
-		hostname:     binding.Hostname,
-		writeTimeout: netConf.WriteTimeout,
-		dialFunc:     df,
-		scheme:       "syslog",
-		egressMetric: egressMetric,
+		endpoints:      newEndpoints(binding, netConf.FailbackInterval),
+		appID:          binding.AppID,
+		hostname:       binding.Hostname,
+		writeTimeout:   netConf.WriteTimeout,
+		bufferSize:     netConf.WriteBufferSize,
+		linger:         netConf.WriteLinger,
+		dialFunc:       df,
+		scheme:         scheme,
+		balance:        balanceMode(binding),
+		resolver:       r,
+		resolveTimeout: netConf.DialTimeout,
+		egressMetric:   egressMetric,
 	}
+	w.messages = newMessageWriter(binding, netConf, w.writeMessage)
 
 	return w
 }
 
 func (w *TCPWriter) connection() (net.Conn, error) {
-	if w.conn == nil {
-		return w.connect()
+	if w.pool.empty() {
+		if err := w.connect(); err != nil {
+			return nil, err
+		}
+	} else if w.endpoints.failbackDue() {
+		w.failback()
 	}
-	return w.conn, nil
+
+	return w.pool.get(), nil
 }
 
-func (w *TCPWriter) connect() (net.Conn, error) {
-	conn, err := w.dialFunc(w.url.Host)
-	if err != nil {
-		return nil, err
+func (w *TCPWriter) connect() error {
+	var err error
+	for _, i := range w.endpoints.order() {
+		var pool connPool
+		pool, err = w.dial(w.endpoints.urls[i])
+		if err != nil {
+			continue
+		}
+
+		w.pool = pool
+		w.endpoints.use(i)
+
+		return nil
 	}
-	w.conn = conn
 
-	log.Printf("created conn to syslog drain: %s", w.url.Host)
+	return err
+}
+
+// failback replaces the active connections with connections to a more
+// preferred drain if one has become reachable again.
+func (w *TCPWriter) failback() {
+	for i := 0; i < w.endpoints.active; i++ {
+		pool, err := w.dial(w.endpoints.urls[i])
+		if err != nil {
+			continue
+		}
+
+		_ = w.pool.close()
+		w.pool = pool
+		w.endpoints.use(i)
 
-	return conn, nil
+		return
+	}
+
+	w.endpoints.use(w.endpoints.active)
 }
 
-// Close tears down any active connections to the drain and prevents reconnect.
-func (w *TCPWriter) Close() error {
-	if w.conn != nil {
-		err := w.conn.Close()
-		w.conn = nil
+// dial resolves the drain URL and connects to its targets. Round-robin
+// balancing connects to every reachable target, otherwise only the first
+// reachable target is used. Least-connection balancing tries the targets
+// with the fewest open connections first.
+func (w *TCPWriter) dial(u *url.URL) (connPool, error) {
+	var pool connPool
 
-		return err
+	targets, err := resolveTargets(
+		w.resolver,
+		u,
+		w.balance != balanceNone,
+		w.resolveTimeout,
+	)
+	if err != nil {
+		log.Printf("failed to resolve syslog drain %s: %s", u.Host, err)
+		return pool, err
 	}
 
-	return nil
+	if w.balance == balanceLeastConn {
+		openConns.sort(targets)
+	}
+
+	for _, t := range targets {
+		var conn net.Conn
+		conn, err = w.dialFunc(t.addr, t.serverName)
+		if err != nil {
+			log.Printf("failed to create conn to syslog drain %s: %s", t.addr, err)
+			continue
+		}
+
+		log.Printf("created conn to syslog drain: %s", t.addr)
+		if w.bufferSize > 0 {
+			conn = newBufferedConn(conn, w.bufferSize, w.linger, w.writeTimeout, w.egressMetric)
+		}
+		pool.add(t.addr, conn)
+
+		if w.balance != balanceRoundRobin {
+			break
+		}
+	}
+
+	if pool.empty() {
+		return pool, err
+	}
+
+	return pool, nil
+}
+
+// Close tears down any active connections to the drain and prevents reconnect.
+func (w *TCPWriter) Close() error {
+	return w.pool.close()
 }
 
 func generateRFC5424Messages(
@@ -191,21 +292,28 @@ func generateRFC5424Messages(
 
 // Write writes an envelope to the syslog drain connection.
 func (w *TCPWriter) Write(env *loggregator_v2.Envelope) error {
-	msgs := generateRFC5424Messages(env, w.hostname, w.appID)
+	return w.messages.writeMessages(env, generateRFC5424Messages(env, w.hostname, w.appID))
+}
+
+func (w *TCPWriter) writeMessage(msg rfc5424.Message) error {
 	conn, err := w.connection()
 	if err != nil {
 		return err
 	}
 
-	for _, msg := range msgs {
+	// Buffered connections apply the write timeout when they flush.
+	if w.bufferSize == 0 {
 		conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
-		_, err = msg.WriteTo(conn)
-		if err != nil {
-			_ = w.Close()
+	}
+	_, err = msg.WriteTo(conn)
+	if err != nil {
+		_ = w.Close()
 
-			return err
-		}
+		return err
+	}
 
+	// Buffered connections count messages once they are flushed.
+	if w.bufferSize == 0 {
 		w.egressMetric.Increment(1)
 	}
 
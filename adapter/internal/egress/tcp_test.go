@@ -2,10 +2,13 @@ package egress_test
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
@@ -215,6 +218,525 @@ var _ = Describe("TCPWriter", func() {
 		})
 	})
 
+	Describe("with fallback drains", func() {
+		var (
+			primaryAddr string
+			fallback    net.Listener
+			writer      egress.WriteCloser
+		)
+
+		BeforeEach(func() {
+			primary, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			primaryAddr = primary.Addr().String()
+			primary.Close()
+
+			fallback, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+
+			primaryURL, _ := url.Parse(fmt.Sprintf("syslog://%s", primaryAddr))
+			fallbackURL, _ := url.Parse(fmt.Sprintf("syslog://%s", fallback.Addr()))
+
+			writer = egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:     "test-app-id",
+					Hostname:  "test-hostname",
+					URL:       primaryURL,
+					Fallbacks: []*url.URL{fallbackURL},
+				},
+				netConf,
+				false,
+				&testhelper.SpyMetric{},
+			)
+		})
+
+		AfterEach(func() {
+			writer.Close()
+			fallback.Close()
+		})
+
+		It("writes to the fallback when the primary is unreachable", func() {
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := fallback.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			actual, err := buf.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal("89 <14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n"))
+		})
+
+		It("fails back to the primary once it is reachable", func() {
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			primary, err := net.Listen("tcp", primaryAddr)
+			Expect(err).ToNot(HaveOccurred())
+			defer primary.Close()
+
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := primary.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			actual, err := buf.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal("89 <14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n"))
+		})
+	})
+
+	Describe("with balancing", func() {
+		DescribeTable("writes messages to the resolved drain addresses", func(mode string) {
+			u, _ := url.Parse(fmt.Sprintf("syslog://%s", listener.Addr()))
+			writer := egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+					Options:  url.Values{"balance": {mode}},
+				},
+				netConf,
+				false,
+				&testhelper.SpyMetric{},
+			)
+			defer writer.Close()
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			for i := 0; i < 2; i++ {
+				actual, err := buf.ReadString('\n')
+				Expect(err).ToNot(HaveOccurred())
+				Expect(actual).To(Equal("89 <14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n"))
+			}
+		},
+			Entry("round-robin", "round-robin"),
+			Entry("least-conn", "least-conn"),
+		)
+
+		It("connects to the targets of the SRV records of drains without a port", func() {
+			_, port, err := net.SplitHostPort(listener.Addr().String())
+			Expect(err).ToNot(HaveOccurred())
+			p, err := strconv.Atoi(port)
+			Expect(err).ToNot(HaveOccurred())
+
+			resolver := &spyResolver{
+				srv: map[string][]*net.SRV{
+					"drain.example.com": {{Target: "127.0.0.1.", Port: uint16(p)}},
+				},
+			}
+			conf := netConf
+			conf.Resolver = resolver
+
+			u, _ := url.Parse("syslog://drain.example.com")
+			writer := egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+				},
+				conf,
+				false,
+				&testhelper.SpyMetric{},
+			)
+			defer writer.Close()
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(resolver.srvNames).To(Equal([]string{"drain.example.com"}))
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			actual, err := bufio.NewReader(conn).ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal("89 <14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n"))
+		})
+
+		It("returns an error when the drain host has no SRV records", func() {
+			conf := netConf
+			conf.Resolver = &spyResolver{}
+
+			u, _ := url.Parse("syslog://drain.example.com")
+			writer := egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+				},
+				conf,
+				false,
+				&testhelper.SpyMetric{},
+			)
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).ToNot(Succeed())
+		})
+
+		It("does not look up SRV records of IP addresses", func() {
+			resolver := &spyResolver{}
+			conf := netConf
+			conf.Resolver = resolver
+
+			u, _ := url.Parse("syslog://127.0.0.1")
+			writer := egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+				},
+				conf,
+				false,
+				&testhelper.SpyMetric{},
+			)
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			_ = writer.Write(env)
+			Expect(resolver.srvNames).To(BeEmpty())
+		})
+	})
+
+	Describe("with sampling", func() {
+		var sampledWriter = func(query string, metric *testhelper.SpyMetric) egress.WriteCloser {
+			u, _ := url.Parse(fmt.Sprintf("syslog://%s", listener.Addr()))
+			options, _ := url.ParseQuery(query)
+			return egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+					Options:  options,
+				},
+				netConf,
+				false,
+				metric,
+			)
+		}
+
+		It("writes the sampled fraction of logs", func() {
+			egressCounter := &testhelper.SpyMetric{}
+			writer := sampledWriter("sample-rate=0.5", egressCounter)
+			defer writer.Close()
+
+			for i := 0; i < 1000; i++ {
+				env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+				env.Timestamp = int64(i)
+				Expect(writer.Write(env)).To(Succeed())
+			}
+
+			Expect(egressCounter.Delta()).To(BeNumerically("~", 500, 100))
+		})
+
+		It("makes the same decision for the same log", func() {
+			first := &testhelper.SpyMetric{}
+			second := &testhelper.SpyMetric{}
+			firstWriter := sampledWriter("sample-rate=0.5", first)
+			defer firstWriter.Close()
+			secondWriter := sampledWriter("sample-rate=0.5", second)
+			defer secondWriter.Close()
+
+			for i := 0; i < 100; i++ {
+				env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+				env.Timestamp = int64(i)
+				Expect(firstWriter.Write(env)).To(Succeed())
+				Expect(secondWriter.Write(env)).To(Succeed())
+
+				Expect(first.Delta()).To(Equal(second.Delta()))
+			}
+		})
+
+		It("keeps all stderr logs in stdout sample mode", func() {
+			egressCounter := &testhelper.SpyMetric{}
+			writer := sampledWriter("sample-rate=0.01&sample-mode=stdout", egressCounter)
+			defer writer.Close()
+
+			for i := 0; i < 100; i++ {
+				env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_ERR)
+				env.Timestamp = int64(i)
+				Expect(writer.Write(env)).To(Succeed())
+			}
+
+			Expect(egressCounter.Delta()).To(Equal(uint64(100)))
+		})
+
+		It("annotates sampled logs with the sample rate", func() {
+			egressCounter := &testhelper.SpyMetric{}
+			writer := sampledWriter("sample-rate=0.5", egressCounter)
+			defer writer.Close()
+
+			for i := 0; egressCounter.Delta() == 0; i++ {
+				env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+				env.Timestamp = int64(i)
+				Expect(writer.Write(env)).To(Succeed())
+			}
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			actual, err := buf.ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(ContainSubstring(`[APP/2] - [sample@47450 rate="0.5"] just a test`))
+		})
+	})
+
+	Describe("with message IDs", func() {
+		var readLine = func(query string, conf egress.NetworkTimeoutConfig) string {
+			u, _ := url.Parse(fmt.Sprintf("syslog://%s", listener.Addr()))
+			options, _ := url.ParseQuery(query)
+			writer := egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+					Options:  options,
+				},
+				conf,
+				false,
+				&testhelper.SpyMetric{},
+			)
+			defer writer.Close()
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			actual, err := bufio.NewReader(conn).ReadString('\n')
+			Expect(err).ToNot(HaveOccurred())
+
+			return actual
+		}
+
+		It("adds the same ID to a log for every replica of the drain", func() {
+			first := readLine("replicas=2", netConf)
+			second := readLine("replicas=2", netConf)
+
+			Expect(first).To(MatchRegexp(`\[APP/2\] - \[dedup@47450 id="[0-9a-f]{32}"\] just a test`))
+			Expect(second).To(Equal(first))
+		})
+
+		It("adds IDs to all drains if enabled", func() {
+			conf := netConf
+			conf.MessageIDs = true
+
+			Expect(readLine("", conf)).To(ContainSubstring("[dedup@47450 id="))
+		})
+
+		It("does not add IDs to drains with a single replica", func() {
+			Expect(readLine("replicas=1", netConf)).ToNot(ContainSubstring("dedup@47450"))
+		})
+	})
+
+	Describe("with a maximum message size", func() {
+		var (
+			writer  egress.WriteCloser
+			payload = strings.Repeat("0123456789", 20)
+		)
+
+		var readMessages = func(n, max int) []string {
+			conn, err := listener.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			buf := bufio.NewReader(conn)
+
+			var msgs []string
+			for i := 0; i < n; i++ {
+				var size int
+				_, err := fmt.Fscanf(buf, "%d ", &size)
+				Expect(err).ToNot(HaveOccurred())
+
+				msg := make([]byte, size)
+				_, err = io.ReadFull(buf, msg)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(msg)).To(BeNumerically("<=", max))
+
+				msgs = append(msgs, string(msg))
+			}
+
+			return msgs
+		}
+
+		var sizedWriter = func(query string) egress.WriteCloser {
+			u, _ := url.Parse(fmt.Sprintf("syslog://%s", listener.Addr()))
+			options, _ := url.ParseQuery(query)
+			return egress.NewTCPWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+					Options:  options,
+				},
+				netConf,
+				false,
+				&testhelper.SpyMetric{},
+			)
+		}
+
+		AfterEach(func() {
+			writer.Close()
+		})
+
+		It("truncates oversized messages with a marker", func() {
+			writer = sizedWriter("max-message-size=100")
+
+			env := buildLogEnvelope("APP", "2", payload, loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			msgs := readMessages(1, 100)
+			Expect(msgs[0]).To(HavePrefix("<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - 0123"))
+			Expect(msgs[0]).To(HaveSuffix("[truncated]\n"))
+			Expect(msgs[0]).To(HaveLen(100))
+		})
+
+		It("splits oversized messages into fragments", func() {
+			writer = sizedWriter("max-message-size=150&max-message-mode=split")
+
+			env := buildLogEnvelope("APP", "2", payload, loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			prefix := "<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - "
+			var body string
+			for i, msg := range readMessages(6, 150) {
+				sd := fmt.Sprintf(`[fragment@47450 index="%d" count="6"] `, i+1)
+				Expect(msg).To(HavePrefix(prefix + sd))
+				Expect(msg).To(HaveSuffix("\n"))
+
+				body += strings.TrimSuffix(strings.TrimPrefix(msg, prefix+sd), "\n")
+			}
+			Expect(body).To(Equal(payload))
+		})
+
+		It("does not change messages within the limit", func() {
+			writer = sizedWriter("max-message-size=100")
+
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			Expect(writer.Write(env)).To(Succeed())
+
+			Expect(readMessages(1, 100)).To(ConsistOf(
+				"<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n",
+			))
+		})
+	})
+
+	Describe("with buffered writes", func() {
+		var (
+			writer       egress.WriteCloser
+			received     chan string
+			egressMetric *testhelper.SpyMetric
+			msg          = "<14>1 1970-01-01T00:00:00.012345+00:00 test-hostname test-app-id [APP/2] - - just a test\n"
+			framed       = fmt.Sprintf("%d %s", len(msg), msg)
+		)
+
+		var bufferedWriter = func(size int, linger time.Duration) egress.WriteCloser {
+			conf := netConf
+			conf.WriteBufferSize = size
+			conf.WriteLinger = linger
+
+			return egress.NewTCPWriter(
+				binding,
+				conf,
+				false,
+				egressMetric,
+			)
+		}
+
+		var writeLogs = func(n int) {
+			env := buildLogEnvelope("APP", "2", "just a test", loggregator_v2.Log_OUT)
+			for i := 0; i < n; i++ {
+				Expect(writer.Write(env)).To(Succeed())
+			}
+		}
+
+		BeforeEach(func() {
+			egressMetric = &testhelper.SpyMetric{}
+			received = make(chan string, 100)
+			go func(received chan<- string) {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+
+				b := make([]byte, 4096)
+				for {
+					n, err := conn.Read(b)
+					if n > 0 {
+						received <- string(b[:n])
+					}
+					if err != nil {
+						return
+					}
+				}
+			}(received)
+		})
+
+		var readAll = func() string {
+			var data string
+			for {
+				select {
+				case d := <-received:
+					data += d
+				default:
+					return data
+				}
+			}
+		}
+
+		It("coalesces messages until the linger interval has passed", func() {
+			writer = bufferedWriter(4096, 500*time.Millisecond)
+			defer writer.Close()
+
+			writeLogs(3)
+
+			Consistently(received, 250*time.Millisecond).ShouldNot(Receive())
+			Eventually(received).Should(Receive(Equal(strings.Repeat(framed, 3))))
+		})
+
+		It("writes the buffered messages when the buffer is full", func() {
+			writer = bufferedWriter(len(framed)*2, time.Hour)
+			defer writer.Close()
+
+			writeLogs(3)
+
+			Eventually(received).Should(Receive(Equal(strings.Repeat(framed, 2))))
+			Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("writes the buffered messages when it is closed", func() {
+			writer = bufferedWriter(4096, time.Hour)
+
+			writeLogs(2)
+			Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+
+			Expect(writer.Close()).To(Succeed())
+
+			var data string
+			Eventually(func() string {
+				data += readAll()
+				return data
+			}).Should(Equal(strings.Repeat(framed, 2)))
+		})
+
+		It("counts the messages once they are written", func() {
+			writer = bufferedWriter(len(framed)*2, 500*time.Millisecond)
+			defer writer.Close()
+
+			writeLogs(3)
+			Expect(egressMetric.Delta()).To(Equal(uint64(2)))
+
+			Eventually(egressMetric.Delta).Should(Equal(uint64(3)))
+		})
+	})
+
 	Describe("Cancel Context", func() {
 		var (
 			writer egress.WriteCloser
@@ -331,3 +853,17 @@ func buildCounterEnvelope(srcInstance string) *loggregator_v2.Envelope {
 		},
 	}
 }
+
+type spyResolver struct {
+	srv      map[string][]*net.SRV
+	srvNames []string
+}
+
+func (s *spyResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	s.srvNames = append(s.srvNames, name)
+	return "", s.srv[name], nil
+}
+
+func (s *spyResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return []string{host}, nil
+}
@@ -10,7 +10,7 @@ import (
 
 // TLSWriter represents a syslog writer that connects over unencrypted TCP.
 type TLSWriter struct {
-	TCPWriter
+	*TCPWriter
 }
 
 // NetworkTimeoutConfig stores various timeout values.
@@ -18,6 +18,52 @@ type NetworkTimeoutConfig struct {
 	Keepalive    time.Duration
 	DialTimeout  time.Duration
 	WriteTimeout time.Duration
+
+	// FailbackInterval is how long a writer uses a fallback drain before it
+	// attempts to return to a more preferred drain.
+	FailbackInterval time.Duration
+
+	// WriteBufferSize enables coalescing of syslog and syslog-tls writes.
+	// Messages are buffered until the buffer size is reached or WriteLinger
+	// has passed. Zero disables buffering.
+	WriteBufferSize int
+	WriteLinger     time.Duration
+
+	// ClientCert is presented to drains that require mutual TLS if the
+	// binding does not have a client certificate of its own.
+	ClientCert *tls.Certificate
+
+	// TLSPolicy is applied to syslog-tls and HTTPS drain connections. Nil
+	// uses the default policy.
+	TLSPolicy *TLSPolicy
+
+	// Proxy routes drain connections through egress proxies. Nil connects
+	// directly.
+	Proxy *Proxy
+
+	// Resolver looks up the SRV records and addresses of syslog and
+	// syslog-tls drains. Nil uses net.DefaultResolver.
+	Resolver Resolver
+
+	// MessageIDs adds an ID to every message so receivers can drop the
+	// duplicates written by replicas of a binding. Drains that request
+	// more than one replica get IDs regardless.
+	MessageIDs bool
+}
+
+// clientCertificates returns the certificates to present to the drain of
+// the binding. The certificate of the binding takes precedence over the
+// default of the adapter.
+func clientCertificates(binding *URLBinding, netConf NetworkTimeoutConfig) []tls.Certificate {
+	if binding.ClientCert != nil {
+		return []tls.Certificate{*binding.ClientCert}
+	}
+
+	if netConf.ClientCert != nil {
+		return []tls.Certificate{*netConf.ClientCert}
+	}
+
+	return nil
 }
 
 func NewTLSWriter(
@@ -31,22 +77,35 @@ func NewTLSWriter(
 		Timeout:   netConf.DialTimeout,
 		KeepAlive: netConf.Keepalive,
 	}
-	df := func(addr string) (net.Conn, error) {
-		return tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
-			InsecureSkipVerify: skipCertVerify,
-		})
+	tlsConfig := drainTLSConfig(binding, netConf, skipCertVerify)
+	proxy := netConf.Proxy.forDrain(binding)
+	df := func(addr, serverName string) (net.Conn, error) {
+		conf := tlsConfig.Clone()
+		if conf.ServerName == "" {
+			conf.ServerName = serverName
+		}
+
+		conn, err := proxy.dial(dialer, addr, serverName)
+		if err != nil {
+			return nil, err
+		}
+
+		if dialer.Timeout > 0 {
+			conn.SetDeadline(time.Now().Add(dialer.Timeout))
+		}
+
+		tlsConn := tls.Client(conn, conf)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn.SetDeadline(time.Time{})
+
+		return tlsConn, nil
 	}
 
 	w := &TLSWriter{
-		TCPWriter{
-			url:          binding.URL,
-			appID:        binding.AppID,
-			hostname:     binding.Hostname,
-			writeTimeout: netConf.WriteTimeout,
-			dialFunc:     df,
-			scheme:       "syslog-tls",
-			egressMetric: egressMetric,
-		},
+		newTCPWriter(binding, netConf, df, "syslog-tls", egressMetric),
 	}
 
 	return w
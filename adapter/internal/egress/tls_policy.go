@@ -0,0 +1,166 @@
+package egress
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var tlsCurves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+}
+
+var defaultTLSPolicy = &TLSPolicy{
+	minVersion: tls.VersionTLS12,
+}
+
+// TLSPolicy is the TLS configuration applied to all syslog-tls and HTTPS
+// drain connections of an adapter.
+type TLSPolicy struct {
+	minVersion   uint16
+	maxVersion   uint16
+	cipherSuites []uint16
+	curves       []tls.CurveID
+	sessions     tls.ClientSessionCache
+}
+
+// NewTLSPolicy returns a TLSPolicy. Versions are given as "1.2" or "1.3",
+// cipher suites by their IANA names and curves as X25519, P256, P384 or
+// P521. Empty values use the defaults of crypto/tls. Sessions are resumed
+// from a cache of the given size. A size of zero disables resumption.
+func NewTLSPolicy(
+	minVersion string,
+	maxVersion string,
+	cipherSuites []string,
+	curves []string,
+	sessionCacheSize int,
+) (*TLSPolicy, error) {
+	p := &TLSPolicy{}
+
+	var err error
+	if p.minVersion, err = parseTLSVersion(minVersion); err != nil {
+		return nil, err
+	}
+
+	if p.maxVersion, err = parseTLSVersion(maxVersion); err != nil {
+		return nil, err
+	}
+
+	if p.maxVersion != 0 && p.minVersion > p.maxVersion {
+		return nil, errors.New("minimum TLS version is above the maximum")
+	}
+
+	suites := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		suites[s.Name] = s.ID
+	}
+
+	for _, name := range cipherSuites {
+		id, ok := suites[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		p.cipherSuites = append(p.cipherSuites, id)
+	}
+
+	for _, name := range curves {
+		id, ok := tlsCurves[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", name)
+		}
+		p.curves = append(p.curves, id)
+	}
+
+	if sessionCacheSize > 0 {
+		p.sessions = tls.NewLRUClientSessionCache(sessionCacheSize)
+	}
+
+	return p, nil
+}
+
+func parseTLSVersion(v string) (uint16, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	version, ok := tlsVersions[v]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q", v)
+	}
+
+	return version, nil
+}
+
+// config returns a new tls.Config with the policy applied. A nil policy
+// returns the default policy.
+func (p *TLSPolicy) config() *tls.Config {
+	if p == nil {
+		p = defaultTLSPolicy
+	}
+
+	return &tls.Config{
+		MinVersion:         p.minVersion,
+		MaxVersion:         p.maxVersion,
+		CipherSuites:       p.cipherSuites,
+		CurvePreferences:   p.curves,
+		ClientSessionCache: p.sessions,
+	}
+}
+
+// drainTLSConfig returns the TLS config for connections to the drain of the
+// binding.
+func drainTLSConfig(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	skipCertVerify bool,
+) *tls.Config {
+	conf := netConf.TLSPolicy.config()
+	conf.InsecureSkipVerify = skipCertVerify
+	conf.Certificates = clientCertificates(binding, netConf)
+	conf.ServerName = binding.ServerName
+	binding.Trust.configure(conf)
+
+	return conf
+}
+
+// handshakeFailure returns why a TLS handshake with a drain failed. It
+// returns false if the error is not a handshake failure.
+func handshakeFailure(err error) (string, bool) {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+		alert            tls.AlertError
+		trust            trustError
+	)
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "certificate signed by unknown authority", true
+	case errors.As(err, &hostname):
+		return "certificate is not valid for " + hostname.Host, true
+	case errors.As(err, &invalid):
+		return invalid.Error(), true
+	case errors.As(err, &recordHeader):
+		return "drain did not respond with TLS", true
+	case errors.As(err, &alert):
+		return "drain rejected the handshake: " + alert.Error(), true
+	case errors.As(err, &trust):
+		return trust.Error(), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,130 @@
+package egress_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLSPolicy", func() {
+	var (
+		server      *httptest.Server
+		serverNames chan string
+		resumed     chan bool
+		env         = buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+	)
+
+	BeforeEach(func() {
+		serverNames = make(chan string, 10)
+		resumed = make(chan bool, 10)
+		server = httptest.NewUnstartedServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				resumed <- r.TLS.DidResume
+			},
+		))
+		server.TLS = &tls.Config{
+			MaxVersion: tls.VersionTLS12,
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				serverNames <- hello.ServerName
+				return nil, nil
+			},
+		}
+		server.StartTLS()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	var write = func(binding *egress.URLBinding, policy *egress.TLSPolicy) error {
+		pool := egress.NewTransportPool(10, nil, nil)
+		writer := pool.NewHTTPSWriter(
+			binding,
+			egress.NetworkTimeoutConfig{TLSPolicy: policy},
+			true,
+			&testhelper.SpyMetric{},
+		)
+
+		return writer.Write(env)
+	}
+
+	It("applies the TLS versions of the policy", func() {
+		binding := buildURLBinding(server.URL, "test-app-id", "test-hostname")
+
+		policy, err := egress.NewTLSPolicy("1.3", "", nil, nil, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(write(binding, policy)).ToNot(Succeed())
+
+		policy, err = egress.NewTLSPolicy("1.2", "1.2", nil, nil, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(write(binding, policy)).To(Succeed())
+	})
+
+	It("applies the cipher suites of the policy", func() {
+		binding := buildURLBinding(server.URL, "test-app-id", "test-hostname")
+
+		policy, err := egress.NewTLSPolicy(
+			"1.2",
+			"",
+			[]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			[]string{"X25519", "P256"},
+			0,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(write(binding, policy)).To(Succeed())
+
+		policy, err = egress.NewTLSPolicy(
+			"1.2",
+			"",
+			[]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+			nil,
+			0,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(write(binding, policy)).ToNot(Succeed())
+	})
+
+	It("sends the server name of the binding", func() {
+		binding := buildURLBinding(server.URL, "test-app-id", "test-hostname")
+		binding.ServerName = "drain.example.com"
+
+		Expect(write(binding, nil)).To(Succeed())
+		Expect(serverNames).To(Receive(Equal("drain.example.com")))
+	})
+
+	It("resumes sessions", func() {
+		binding := buildURLBinding(server.URL, "test-app-id", "test-hostname")
+
+		policy, err := egress.NewTLSPolicy("1.2", "", nil, nil, 10)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(write(binding, policy)).To(Succeed())
+		Expect(resumed).To(Receive(BeFalse()))
+
+		Expect(write(binding, policy)).To(Succeed())
+		Expect(resumed).To(Receive(BeTrue()))
+	})
+
+	DescribeTable("returns an error for an invalid policy", func(
+		minVersion string,
+		maxVersion string,
+		cipherSuites []string,
+		curves []string,
+	) {
+		_, err := egress.NewTLSPolicy(minVersion, maxVersion, cipherSuites, curves, 0)
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("unknown version", "1.4", "", nil, nil),
+		Entry("minimum above maximum", "1.3", "1.2", nil, nil),
+		Entry("unknown cipher suite", "1.2", "", []string{"TLS_NOPE"}, nil),
+		Entry("unknown curve", "1.2", "", nil, []string{"P128"}),
+	)
+})
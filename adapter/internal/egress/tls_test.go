@@ -85,4 +85,92 @@ var _ = Describe("TLSWriter", func() {
 		By("emit an egress metric for each message")
 		Expect(egressCounter.Delta()).To(Equal(uint64(1)))
 	})
+
+	Describe("with client certificates", func() {
+		var (
+			listener   net.Listener
+			bindingCrt tls.Certificate
+			defaultCrt tls.Certificate
+		)
+
+		BeforeEach(func() {
+			var err error
+			tlsConfig.ClientAuth = tls.RequireAnyClientCert
+			listener, err = tls.Listen("tcp", ":0", tlsConfig)
+			Expect(err).ToNot(HaveOccurred())
+
+			bindingCrt, err = tls.X509KeyPair(
+				test_util.MustAsset("adapter.crt"),
+				test_util.MustAsset("adapter.key"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			defaultCrt, err = tls.X509KeyPair(
+				test_util.MustAsset("scheduler.crt"),
+				test_util.MustAsset("scheduler.key"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		var peerCertificate = func(binding *egress.URLBinding, conf egress.NetworkTimeoutConfig) []byte {
+			writer := egress.NewTLSWriter(
+				binding,
+				conf,
+				true,
+				&testhelper.SpyMetric{},
+			)
+			defer writer.Close()
+
+			peer := make(chan []byte, 1)
+			go func() {
+				defer GinkgoRecover()
+
+				conn, err := listener.Accept()
+				Expect(err).ToNot(HaveOccurred())
+				defer conn.Close()
+
+				tlsConn := conn.(*tls.Conn)
+				Expect(tlsConn.Handshake()).To(Succeed())
+				peer <- tlsConn.ConnectionState().PeerCertificates[0].Raw
+			}()
+
+			Expect(writer.Write(env)).To(Succeed())
+
+			var raw []byte
+			Eventually(peer).Should(Receive(&raw))
+
+			return raw
+		}
+
+		var buildBinding = func() *egress.URLBinding {
+			url, _ := url.Parse(fmt.Sprintf("syslog-tls://%s", listener.Addr()))
+
+			return &egress.URLBinding{
+				AppID:    "test-app-id",
+				Hostname: "test-hostname",
+				URL:      url,
+			}
+		}
+
+		It("presents the client certificate of the binding", func() {
+			binding := buildBinding()
+			binding.ClientCert = &bindingCrt
+
+			conf := netConf
+			conf.ClientCert = &defaultCrt
+
+			Expect(peerCertificate(binding, conf)).To(Equal(bindingCrt.Certificate[0]))
+		})
+
+		It("presents the default client certificate", func() {
+			conf := netConf
+			conf.ClientCert = &defaultCrt
+
+			Expect(peerCertificate(buildBinding(), conf)).To(Equal(defaultCrt.Certificate[0]))
+		})
+	})
 })
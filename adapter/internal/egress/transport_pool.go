@@ -0,0 +1,347 @@
+package egress
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+)
+
+// defaultMaxConnsPerHost is the connection limit per drain host of the
+// transport pool used by NewHTTPSWriter.
+const defaultMaxConnsPerHost = 50
+
+var defaultTransportPool = NewTransportPool(defaultMaxConnsPerHost, nil, nil)
+
+// transportKey identifies the settings a transport was created with. HTTPS
+// writers with the same settings share a transport. Client certificates are
+// identified by the hash of their leaf certificate and drain trusts by the
+// hash of their CA bundle and pins. The TLS policy and the proxy are shared
+// by the writers of an adapter and identified by their address.
+type transportKey struct {
+	skipCertVerify bool
+	dialTimeout    time.Duration
+	keepalive      time.Duration
+	clientCert     [sha256.Size]byte
+	trust          [sha256.Size]byte
+	policy         *TLSPolicy
+	serverName     string
+	proxy          *Proxy
+}
+
+// TransportPool shares HTTP transports between the HTTPS writers of an
+// adapter. Each transport pools connections per drain host, bounded by the
+// maximum number of connections per host, and negotiates HTTP/2 with drains
+// that support it. A transport is removed from the pool and its idle
+// connections are closed once the last writer using it is closed.
+type TransportPool struct {
+	mu              sync.Mutex
+	transports      map[transportKey]*pooledTransport
+	maxConnsPerHost int
+	stats           *connStats
+}
+
+// NewTransportPool returns a TransportPool. A maxConnsPerHost of zero means
+// no limit. The metrics report the number of open and idle connections and
+// may be nil.
+func NewTransportPool(
+	maxConnsPerHost int,
+	openMetric pulseemitter.GaugeMetric,
+	idleMetric pulseemitter.GaugeMetric,
+) *TransportPool {
+	return &TransportPool{
+		transports:      make(map[transportKey]*pooledTransport),
+		maxConnsPerHost: maxConnsPerHost,
+		stats: &connStats{
+			openMetric: openMetric,
+			idleMetric: idleMetric,
+		},
+	}
+}
+
+// NewHTTPSWriter creates a new HTTPS syslog writer that uses the transports
+// of the pool. It is a WriterConstructor.
+func (p *TransportPool) NewHTTPSWriter(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	skipCertVerify bool,
+	egressMetric pulseemitter.CounterMetric,
+) WriteCloser {
+	key, t := p.acquire(binding, netConf, skipCertVerify)
+	client := &http.Client{
+		Transport: t,
+		Timeout:   60 * time.Second,
+	}
+
+	w := newHTTPSWriter(binding, netConf, client, egressMetric)
+	w.release = func() {
+		p.release(key)
+	}
+
+	return w
+}
+
+// openConnections returns the number of open connections of all transports
+// of the pool.
+func (p *TransportPool) openConnections() int {
+	if p == nil {
+		return 0
+	}
+
+	return int(atomic.LoadInt64(&p.stats.open))
+}
+
+// pooledTransport is a transport of the pool and the number of writers
+// using it.
+type pooledTransport struct {
+	transport *countingTransport
+	refs      int
+}
+
+// acquire returns the transport for the settings of the binding and adds a
+// reference to it. Each call must be matched by a call to release.
+func (p *TransportPool) acquire(
+	binding *URLBinding,
+	netConf NetworkTimeoutConfig,
+	skipCertVerify bool,
+) (transportKey, http.RoundTripper) {
+	key := transportKey{
+		skipCertVerify: skipCertVerify,
+		dialTimeout:    netConf.DialTimeout,
+		keepalive:      netConf.Keepalive,
+		policy:         netConf.TLSPolicy,
+		serverName:     binding.ServerName,
+		proxy:          netConf.Proxy.forDrain(binding),
+	}
+	if certs := clientCertificates(binding, netConf); len(certs) > 0 {
+		key.clientCert = sha256.Sum256(certs[0].Certificate[0])
+	}
+	if binding.Trust != nil {
+		key.trust = binding.Trust.id
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pt, ok := p.transports[key]; ok {
+		pt.refs++
+		return key, pt.transport
+	}
+
+	tlsConfig := drainTLSConfig(binding, netConf, skipCertVerify)
+
+	dialer := &net.Dialer{
+		Timeout:   netConf.DialTimeout,
+		KeepAlive: netConf.Keepalive,
+	}
+
+	t := &countingTransport{
+		stats: p.stats,
+		transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := dialer.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+
+				return p.stats.track(conn), nil
+			},
+			Proxy:                 key.proxy.https(),
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   p.maxConnsPerHost,
+			MaxConnsPerHost:       p.maxConnsPerHost,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			TLSClientConfig:       tlsConfig,
+			ForceAttemptHTTP2:     true,
+		},
+	}
+	p.transports[key] = &pooledTransport{
+		transport: t,
+		refs:      1,
+	}
+
+	return key, t
+}
+
+// release removes a reference to the transport. The transport of the last
+// reference is removed from the pool and its idle connections are closed.
+func (p *TransportPool) release(key transportKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pt, ok := p.transports[key]
+	if !ok {
+		return
+	}
+
+	pt.refs--
+	if pt.refs > 0 {
+		return
+	}
+
+	delete(p.transports, key)
+	pt.transport.transport.CloseIdleConnections()
+}
+
+// connStats counts the open connections of a pool and the connections that
+// are in use by at least one request. Connections that are open but not in
+// use are idle. An HTTP/2 connection that carries several requests is
+// counted once.
+type connStats struct {
+	open       int64
+	active     int64
+	openMetric pulseemitter.GaugeMetric
+	idleMetric pulseemitter.GaugeMetric
+}
+
+func (s *connStats) track(conn net.Conn) net.Conn {
+	s.add(&s.open, 1)
+
+	return &countingConn{
+		Conn:  conn,
+		stats: s,
+	}
+}
+
+func (s *connStats) add(counter *int64, delta int64) {
+	atomic.AddInt64(counter, delta)
+
+	open := atomic.LoadInt64(&s.open)
+	idle := open - atomic.LoadInt64(&s.active)
+	// A connection that is closed while a request still uses it is counted
+	// as active until the request is done.
+	if idle < 0 {
+		idle = 0
+	}
+
+	if s.openMetric != nil {
+		s.openMetric.Set(float64(open))
+	}
+
+	if s.idleMetric != nil {
+		s.idleMetric.Set(float64(idle))
+	}
+}
+
+// countingConn decrements the open connections once it is closed and
+// counts the requests that are using it.
+type countingConn struct {
+	net.Conn
+	stats    *connStats
+	once     sync.Once
+	requests int64
+}
+
+// use marks the connection as active while requests are using it.
+func (c *countingConn) use() {
+	if atomic.AddInt64(&c.requests, 1) == 1 {
+		c.stats.add(&c.stats.active, 1)
+	}
+}
+
+// done marks the connection as idle once no request is using it.
+func (c *countingConn) done() {
+	if atomic.AddInt64(&c.requests, -1) == 0 {
+		c.stats.add(&c.stats.active, -1)
+	}
+}
+
+// trackedConn returns the countingConn of a connection the transport got
+// for a request, or nil if the connection is not tracked.
+func trackedConn(conn net.Conn) *countingConn {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+
+	c, _ := conn.(*countingConn)
+	return c
+}
+
+func (c *countingConn) Close() error {
+	c.once.Do(func() {
+		c.stats.add(&c.stats.open, -1)
+	})
+
+	return c.Conn.Close()
+}
+
+// countingTransport marks the connection of a request as in use until the
+// response body is closed.
+type countingTransport struct {
+	transport *http.Transport
+	stats     *connStats
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := &connUse{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			u.acquire(trackedConn(info.Conn))
+		},
+	}))
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		u.release()
+		return nil, err
+	}
+
+	resp.Body = &countingBody{
+		ReadCloser: resp.Body,
+		use:        u,
+	}
+
+	return resp, nil
+}
+
+// connUse is the connection a request is using. A request that is retried
+// on another connection releases the previous one.
+type connUse struct {
+	mu   sync.Mutex
+	conn *countingConn
+}
+
+func (u *connUse) acquire(c *countingConn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.conn != nil {
+		u.conn.done()
+	}
+
+	u.conn = c
+	if c != nil {
+		c.use()
+	}
+}
+
+func (u *connUse) release() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.conn != nil {
+		u.conn.done()
+		u.conn = nil
+	}
+}
+
+type countingBody struct {
+	io.ReadCloser
+	use *connUse
+}
+
+func (b *countingBody) Close() error {
+	b.use.release()
+
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,240 @@
+package egress_test
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/test_util"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransportPool", func() {
+	var (
+		drain      *SpyDrain
+		openMetric *testhelper.SpyMetric
+		idleMetric *testhelper.SpyMetric
+		env        *loggregator_v2.Envelope
+	)
+
+	BeforeEach(func() {
+		drain = newMockOKDrain()
+		openMetric = &testhelper.SpyMetric{}
+		idleMetric = &testhelper.SpyMetric{}
+		env = buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+	})
+
+	AfterEach(func() {
+		drain.Close()
+	})
+
+	It("shares connections between writers to the same host", func() {
+		pool := egress.NewTransportPool(10, openMetric, idleMetric)
+
+		for i := 0; i < 5; i++ {
+			writer := pool.NewHTTPSWriter(
+				buildURLBinding(drain.URL, "test-app-id", "test-hostname"),
+				egress.NetworkTimeoutConfig{},
+				true,
+				&testhelper.SpyMetric{},
+			)
+
+			Expect(writer.Write(env)).To(Succeed())
+		}
+
+		Expect(drain.messages).To(HaveLen(5))
+		Expect(openMetric.GaugeValue()).To(Equal(1.0))
+		Expect(idleMetric.GaugeValue()).To(Equal(1.0))
+	})
+
+	It("closes the connections of a transport once its last writer is closed", func() {
+		pool := egress.NewTransportPool(10, openMetric, idleMetric)
+
+		var writers []egress.WriteCloser
+		for i := 0; i < 2; i++ {
+			writer := pool.NewHTTPSWriter(
+				buildURLBinding(drain.URL, "test-app-id", "test-hostname"),
+				egress.NetworkTimeoutConfig{},
+				true,
+				&testhelper.SpyMetric{},
+			)
+			Expect(writer.Write(env)).To(Succeed())
+			writers = append(writers, writer)
+		}
+		Expect(openMetric.GaugeValue()).To(Equal(1.0))
+
+		Expect(writers[0].Close()).To(Succeed())
+		Consistently(openMetric.GaugeValue).Should(Equal(1.0))
+
+		Expect(writers[1].Close()).To(Succeed())
+		Eventually(openMetric.GaugeValue).Should(Equal(0.0))
+	})
+
+	It("creates a new transport for writers after the last writer is closed", func() {
+		pool := egress.NewTransportPool(10, openMetric, idleMetric)
+
+		writer := pool.NewHTTPSWriter(
+			buildURLBinding(drain.URL, "test-app-id", "test-hostname"),
+			egress.NetworkTimeoutConfig{},
+			true,
+			&testhelper.SpyMetric{},
+		)
+		Expect(writer.Write(env)).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+		Eventually(openMetric.GaugeValue).Should(Equal(0.0))
+
+		writer = pool.NewHTTPSWriter(
+			buildURLBinding(drain.URL, "test-app-id", "test-hostname"),
+			egress.NetworkTimeoutConfig{},
+			true,
+			&testhelper.SpyMetric{},
+		)
+		Expect(writer.Write(env)).To(Succeed())
+		Expect(openMetric.GaugeValue()).To(Equal(1.0))
+		Expect(drain.messages).To(HaveLen(2))
+	})
+
+	It("limits the connections per host", func() {
+		var (
+			mu    sync.Mutex
+			conns int
+		)
+		server := httptest.NewUnstartedServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(10 * time.Millisecond)
+			},
+		))
+		server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				mu.Lock()
+				defer mu.Unlock()
+				conns++
+			}
+		}
+		server.StartTLS()
+		defer server.Close()
+
+		pool := egress.NewTransportPool(2, openMetric, idleMetric)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			writer := pool.NewHTTPSWriter(
+				buildURLBinding(server.URL, "test-app-id", "test-hostname"),
+				egress.NetworkTimeoutConfig{},
+				true,
+				&testhelper.SpyMetric{},
+			)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+
+				for j := 0; j < 5; j++ {
+					Expect(writer.Write(env)).To(Succeed())
+				}
+			}()
+		}
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(conns).To(BeNumerically("<=", 2))
+	})
+
+	It("counts HTTP/2 connections with concurrent requests as active once", func() {
+		arrived := make(chan struct{}, 10)
+		release := make(chan struct{})
+		busy := httptest.NewUnstartedServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				arrived <- struct{}{}
+				<-release
+			},
+		))
+		busy.EnableHTTP2 = true
+		busy.StartTLS()
+		defer busy.Close()
+
+		quiet := httptest.NewUnstartedServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {},
+		))
+		quiet.EnableHTTP2 = true
+		quiet.StartTLS()
+		defer quiet.Close()
+
+		pool := egress.NewTransportPool(10, openMetric, idleMetric)
+		newWriter := func(u string) egress.WriteCloser {
+			return pool.NewHTTPSWriter(
+				buildURLBinding(u, "test-app-id", "test-hostname"),
+				egress.NetworkTimeoutConfig{},
+				true,
+				&testhelper.SpyMetric{},
+			)
+		}
+
+		Expect(newWriter(quiet.URL).Write(env)).To(Succeed())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			writer := newWriter(busy.URL)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				writer.Write(env)
+			}()
+		}
+		for i := 0; i < 3; i++ {
+			Eventually(arrived).Should(Receive())
+		}
+
+		Expect(openMetric.GaugeValue()).To(Equal(2.0))
+		Expect(idleMetric.GaugeValue()).To(Equal(1.0))
+
+		close(release)
+		wg.Wait()
+		Expect(idleMetric.GaugeValue()).To(Equal(2.0))
+	})
+
+	It("presents the client certificate of each binding", func() {
+		peers := make(chan []byte, 10)
+		server := httptest.NewUnstartedServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				peers <- r.TLS.PeerCertificates[0].Raw
+			},
+		))
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		server.StartTLS()
+		defer server.Close()
+
+		pool := egress.NewTransportPool(10, openMetric, idleMetric)
+
+		for _, name := range []string{"adapter", "scheduler"} {
+			cert, err := tls.X509KeyPair(
+				test_util.MustAsset(name+".crt"),
+				test_util.MustAsset(name+".key"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			binding := buildURLBinding(server.URL, "test-app-id", "test-hostname")
+			binding.ClientCert = &cert
+			writer := pool.NewHTTPSWriter(
+				binding,
+				egress.NetworkTimeoutConfig{},
+				true,
+				&testhelper.SpyMetric{},
+			)
+
+			Expect(writer.Write(env)).To(Succeed())
+			Expect(peers).To(Receive(Equal(cert.Certificate[0])))
+		}
+	})
+})
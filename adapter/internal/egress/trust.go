@@ -0,0 +1,110 @@
+package egress
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DrainTrust verifies the certificate of a drain against a custom CA bundle,
+// a set of SPKI pins, or both. It takes precedence over skipping certificate
+// verification for the adapter.
+type DrainTrust struct {
+	roots *x509.CertPool
+	pins  map[[sha256.Size]byte]bool
+	id    [sha256.Size]byte
+}
+
+// NewDrainTrust returns a DrainTrust for the PEM encoded CA bundle and the
+// base64 encoded SHA-256 hashes of the subject public key info. It returns
+// nil if neither is given.
+func NewDrainTrust(caCert string, spkiPins []string) (*DrainTrust, error) {
+	if caCert == "" && len(spkiPins) == 0 {
+		return nil, nil
+	}
+
+	t := &DrainTrust{}
+	h := sha256.New()
+
+	if caCert != "" {
+		t.roots = x509.NewCertPool()
+		if !t.roots.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, errors.New("invalid drain CA certificate")
+		}
+		h.Write([]byte(caCert))
+	}
+
+	if len(spkiPins) > 0 {
+		t.pins = make(map[[sha256.Size]byte]bool)
+	}
+
+	for _, p := range spkiPins {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(p))
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid drain SPKI pin %q", p)
+		}
+
+		var pin [sha256.Size]byte
+		copy(pin[:], b)
+		t.pins[pin] = true
+		h.Write(b)
+	}
+
+	copy(t.id[:], h.Sum(nil))
+
+	return t, nil
+}
+
+// configure makes the TLS config verify peers with the trust. A nil trust
+// leaves the config untouched.
+func (t *DrainTrust) configure(c *tls.Config) {
+	if t == nil {
+		return
+	}
+
+	// The default verification is replaced by verifyConnection which checks
+	// the certificate chain against the custom roots.
+	c.InsecureSkipVerify = true
+	c.VerifyConnection = t.verifyConnection
+}
+
+func (t *DrainTrust) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return trustError("drain did not present a certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+
+	if t.roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range cs.PeerCertificates[1:] {
+			intermediates.AddCert(c)
+		}
+
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         t.roots,
+			Intermediates: intermediates,
+			DNSName:       cs.ServerName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.pins != nil && !t.pins[sha256.Sum256(leaf.RawSubjectPublicKeyInfo)] {
+		return trustError("drain certificate does not match any SPKI pin")
+	}
+
+	return nil
+}
+
+// trustError is returned when a drain certificate is rejected by a
+// DrainTrust.
+type trustError string
+
+func (e trustError) Error() string {
+	return string(e)
+}
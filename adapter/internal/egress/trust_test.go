@@ -0,0 +1,238 @@
+package egress_test
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/adapter/internal/test_util"
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DrainTrust", func() {
+	var (
+		server *httptest.Server
+		env    = buildLogEnvelope("APP", "1", "just a test", loggregator_v2.Log_OUT)
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {},
+		))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	var serverCA = func() string {
+		return string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: server.Certificate().Raw,
+		}))
+	}
+
+	var serverPin = func() string {
+		sum := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	var otherPin = func() string {
+		sum := sha256.Sum256([]byte("some-other-key"))
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	var write = func(trust *egress.DrainTrust, skipCertVerify bool) error {
+		binding := buildURLBinding(server.URL, "test-app-id", "test-hostname")
+		binding.Trust = trust
+
+		pool := egress.NewTransportPool(10, nil, nil)
+		writer := pool.NewHTTPSWriter(
+			binding,
+			egress.NetworkTimeoutConfig{},
+			skipCertVerify,
+			&testhelper.SpyMetric{},
+		)
+
+		return writer.Write(env)
+	}
+
+	It("does not verify drains without a trust when told to skip verification", func() {
+		Expect(write(nil, true)).To(Succeed())
+		Expect(write(nil, false)).ToNot(Succeed())
+	})
+
+	It("verifies the drain against the CA bundle", func() {
+		trust, err := egress.NewDrainTrust(serverCA(), nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(write(trust, false)).To(Succeed())
+	})
+
+	It("rejects a drain signed by another CA even when skipping verification", func() {
+		trust, err := egress.NewDrainTrust(
+			string(test_util.MustAsset("scalable-syslog-ca.crt")),
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(write(trust, true)).ToNot(Succeed())
+	})
+
+	It("verifies the drain against the SPKI pins", func() {
+		trust, err := egress.NewDrainTrust("", []string{otherPin(), serverPin()})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(write(trust, false)).To(Succeed())
+	})
+
+	It("rejects a drain that does not match the SPKI pins", func() {
+		trust, err := egress.NewDrainTrust("", []string{otherPin()})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(write(trust, true)).ToNot(Succeed())
+	})
+
+	It("requires the CA bundle and the SPKI pins to match", func() {
+		trust, err := egress.NewDrainTrust(serverCA(), []string{otherPin()})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(write(trust, false)).ToNot(Succeed())
+	})
+
+	It("verifies syslog-tls drains", func() {
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", server.TLS)
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			conn.Read(make([]byte, 1024))
+		}()
+
+		trust, err := egress.NewDrainTrust("", []string{serverPin()})
+		Expect(err).ToNot(HaveOccurred())
+
+		u, _ := url.Parse(fmt.Sprintf("syslog-tls://%s", listener.Addr()))
+		writer := egress.NewTLSWriter(
+			&egress.URLBinding{
+				AppID:    "test-app-id",
+				Hostname: "test-hostname",
+				URL:      u,
+				Trust:    trust,
+			},
+			egress.NetworkTimeoutConfig{
+				WriteTimeout: time.Second,
+				DialTimeout:  time.Second,
+			},
+			false,
+			&testhelper.SpyMetric{},
+		)
+		defer writer.Close()
+
+		Expect(writer.Write(env)).To(Succeed())
+	})
+
+	Describe("with SRV records", func() {
+		var (
+			listener net.Listener
+			port     uint16
+		)
+
+		BeforeEach(func() {
+			var err error
+			listener, err = tls.Listen("tcp", "127.0.0.1:0", server.TLS)
+			Expect(err).ToNot(HaveOccurred())
+
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+
+					go func() {
+						defer conn.Close()
+						conn.Read(make([]byte, 1024))
+					}()
+				}
+			}()
+
+			port = uint16(listener.Addr().(*net.TCPAddr).Port)
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		var writeSRV = func(host string) error {
+			trust, err := egress.NewDrainTrust(serverCA(), nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			u, _ := url.Parse("syslog-tls://" + host)
+			writer := egress.NewTLSWriter(
+				&egress.URLBinding{
+					AppID:    "test-app-id",
+					Hostname: "test-hostname",
+					URL:      u,
+					Trust:    trust,
+				},
+				egress.NetworkTimeoutConfig{
+					WriteTimeout: time.Second,
+					DialTimeout:  time.Second,
+					Resolver: &spyResolver{
+						srv: map[string][]*net.SRV{
+							host: {{Target: "127.0.0.1.", Port: port}},
+						},
+					},
+				},
+				false,
+				&testhelper.SpyMetric{},
+			)
+			defer writer.Close()
+
+			return writer.Write(env)
+		}
+
+		It("verifies the SRV target for the drain host", func() {
+			Expect(writeSRV("example.com")).To(Succeed())
+		})
+
+		It("rejects an SRV target whose certificate does not match the drain host", func() {
+			Expect(writeSRV("drain.example.org")).ToNot(Succeed())
+		})
+	})
+
+	DescribeTable("returns an error for invalid trust settings", func(ca string, pins []string) {
+		_, err := egress.NewDrainTrust(ca, pins)
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("invalid CA bundle", "not a certificate", nil),
+		Entry("pin is not base64", "", []string{"not base64!"}),
+		Entry("pin is not a SHA-256 hash", "", []string{"c2hvcnQ="}),
+	)
+
+	It("returns nil without a CA bundle or pins", func() {
+		trust, err := egress.NewDrainTrust("", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(trust).To(BeNil())
+	})
+})
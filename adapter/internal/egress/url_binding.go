@@ -3,18 +3,58 @@ package egress
 // URLBinding associates a particular application with a syslog URL. The
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 )
 
 // application is identified by AppID and Hostname. The syslog URL is
-// identified by URL.
+// identified by URL. Any fallback drains, in order of preference, are
+// identified by Fallbacks. Headers are added to the requests of HTTPS drains.
+// ClientCert, if set, is presented to drains that require mutual TLS and
+// Trust, if set, verifies the certificate of the drain. ServerName, if set,
+// overrides the server name sent to TLS drains. Options holds the query
+// parameters that configure how the adapter writes to the drain. They are
+// removed from URL so they are not sent to the drain.
 type URLBinding struct {
-	Context  context.Context
-	AppID    string
-	Hostname string
-	URL      *url.URL
+	Context   context.Context
+	AppID     string
+	Hostname  string
+	URL       *url.URL
+	Fallbacks []*url.URL
+	Headers   http.Header
+	// SigningSecret is the shared secret used to sign HTTPS drain requests.
+	SigningSecret []byte
+	ClientCert    *tls.Certificate
+	Trust         *DrainTrust
+	ServerName    string
+	Options       url.Values
+}
+
+// adapterOptions are the query parameters of a drain URL that configure the
+// adapter rather than the drain.
+var adapterOptions = []string{
+	"balance",
+	"rate-limit",
+	"rate-limit-bytes",
+	"rate-limit-burst",
+	"include",
+	"exclude",
+	"source-type",
+	"exclude-source-type",
+	"sample-rate",
+	"sample-mode",
+	"max-message-size",
+	"max-message-mode",
+	"in-flight",
+	"ordering",
+	"replicas",
+	"proxy",
 }
 
 // Scheme is a convenience wrapper around the *url.URL Scheme field
@@ -22,18 +62,232 @@ func (u *URLBinding) Scheme() string {
 	return u.URL.Scheme
 }
 
+// drainHost returns the host of the drain for log messages.
+func (u *URLBinding) drainHost() string {
+	if u.URL == nil {
+		return ""
+	}
+
+	return u.URL.Host
+}
+
+// Endpoints returns the primary drain URL followed by any fallback URLs.
+func (u *URLBinding) Endpoints() []*url.URL {
+	return append([]*url.URL{u.URL}, u.Fallbacks...)
+}
+
 func buildBinding(c context.Context, b *v1.Binding) (*URLBinding, error) {
 	url, err := url.Parse(b.Drain)
 	if err != nil {
 		return nil, err
 	}
 
+	fallbacks, err := parseFallbacks(url)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := parseHeaders(url)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCert, err := parseClientCert(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var pins []string
+	if b.SpkiPins != "" {
+		pins = strings.Split(b.SpkiPins, ",")
+	}
+
+	trust, err := NewDrainTrust(b.CaCert, pins)
+	if err != nil {
+		return nil, err
+	}
+
 	u := &URLBinding{
-		AppID:    b.AppId,
-		URL:      url,
-		Hostname: b.Hostname,
-		Context:  c,
+		AppID:     b.AppId,
+		URL:       url,
+		Fallbacks: fallbacks,
+		Headers:   headers,
+		Hostname:  b.Hostname,
+		Context:   c,
 	}
+	u.SigningSecret = parseSigningSecret(url)
+	u.ClientCert = clientCert
+	u.Trust = trust
+	u.ServerName = parseServerName(url)
+	u.Options = parseOptions(url)
 
 	return u, nil
 }
+
+// parseOptions removes the adapter options from the drain URL and returns
+// them.
+func parseOptions(u *url.URL) url.Values {
+	query := u.Query()
+	options := make(url.Values)
+	for _, name := range adapterOptions {
+		if values, ok := query[name]; ok {
+			options[name] = values
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return options
+}
+
+// parseFallbacks removes the fallback query parameters from the drain URL
+// and returns them in the order they were given. Fallbacks must use the same
+// scheme as the drain.
+func parseFallbacks(u *url.URL) ([]*url.URL, error) {
+	query := u.Query()
+	raw, ok := query["fallback"]
+	if !ok {
+		return nil, nil
+	}
+
+	var fallbacks []*url.URL
+	for _, r := range raw {
+		f, err := url.Parse(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Host == "" {
+			return nil, errors.New("fallback drain URL has no host")
+		}
+
+		if f.Scheme != u.Scheme {
+			return nil, fmt.Errorf(
+				"fallback drain scheme %s does not match %s",
+				f.Scheme, u.Scheme,
+			)
+		}
+
+		fallbacks = append(fallbacks, f)
+	}
+
+	query.Del("fallback")
+	u.RawQuery = query.Encode()
+
+	return fallbacks, nil
+}
+
+// parseHeaders removes the header and bearer-token query parameters from the
+// drain URL and returns the headers they describe. Each header parameter has
+// the form "Name: Value". A bearer token sets the Authorization header. The
+// returned errors do not contain header values as they are often secrets.
+func parseHeaders(u *url.URL) (http.Header, error) {
+	query := u.Query()
+	raw, hasHeaders := query["header"]
+	token := query.Get("bearer-token")
+	if !hasHeaders && token == "" {
+		return nil, nil
+	}
+
+	headers := make(http.Header)
+	for _, r := range raw {
+		parts := strings.SplitN(r, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("drain header is not of the form name: value")
+		}
+
+		name := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if !validHeaderName(name) {
+			return nil, errors.New("drain header has an invalid name")
+		}
+
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("drain header %s has an invalid value", name)
+		}
+
+		headers.Add(name, value)
+	}
+
+	if token != "" {
+		if strings.ContainsAny(token, "\r\n") {
+			return nil, errors.New("drain bearer token is invalid")
+		}
+
+		headers.Set("Authorization", "Bearer "+token)
+	}
+
+	query.Del("header")
+	query.Del("bearer-token")
+	u.RawQuery = query.Encode()
+
+	return headers, nil
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+
+	switch http.CanonicalHeaderKey(name) {
+	case "Host", "Content-Length", "Content-Type", "Transfer-Encoding":
+		return false
+	}
+
+	return true
+}
+
+// parseSigningSecret removes the hmac-secret query parameter from the drain
+// URL and returns its value. It returns nil if the secret is not set.
+func parseSigningSecret(u *url.URL) []byte {
+	query := u.Query()
+	if _, ok := query["hmac-secret"]; !ok {
+		return nil
+	}
+
+	secret := query.Get("hmac-secret")
+	query.Del("hmac-secret")
+	u.RawQuery = query.Encode()
+
+	if secret == "" {
+		return nil
+	}
+
+	return []byte(secret)
+}
+
+// parseClientCert returns the client certificate of the binding, or nil if
+// the binding has none.
+func parseClientCert(b *v1.Binding) (*tls.Certificate, error) {
+	if b.ClientCert == "" && b.ClientKey == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.X509KeyPair([]byte(b.ClientCert), []byte(b.ClientKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid drain client certificate: %s", err)
+	}
+
+	return &cert, nil
+}
+
+// parseServerName removes the sni query parameter from the drain URL and
+// returns its value.
+func parseServerName(u *url.URL) string {
+	query := u.Query()
+	if _, ok := query["sni"]; !ok {
+		return ""
+	}
+
+	serverName := query.Get("sni")
+	query.Del("sni")
+	u.RawQuery = query.Encode()
+
+	return serverName
+}
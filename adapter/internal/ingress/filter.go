@@ -0,0 +1,105 @@
+package ingress
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	v2 "code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+)
+
+// envelopeFilter decides which envelopes of a binding are written to its
+// drain. It is configured with the following drain URL query parameters,
+// each of which may be given more than once:
+//
+//	include              only write logs whose payload matches a regex
+//	exclude              do not write logs whose payload matches a regex
+//	source-type          only write envelopes with a source type, e.g. APP
+//	exclude-source-type  do not write envelopes with a source type, e.g. RTR
+//
+// Source types match themselves and any source type below them, so APP
+// matches APP/PROC/WEB. Payload filters do not apply to metrics.
+type envelopeFilter struct {
+	include            []*regexp.Regexp
+	exclude            []*regexp.Regexp
+	sourceTypes        []string
+	excludeSourceTypes []string
+}
+
+func newEnvelopeFilter(query url.Values) (*envelopeFilter, error) {
+	include, err := compileAll(query["include"])
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := compileAll(query["exclude"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &envelopeFilter{
+		include:            include,
+		exclude:            exclude,
+		sourceTypes:        query["source-type"],
+		excludeSourceTypes: query["exclude-source-type"],
+	}, nil
+}
+
+// allowed reports whether the envelope should be written to the drain.
+func (f *envelopeFilter) allowed(env *v2.Envelope) bool {
+	sourceType := env.GetTags()["source_type"]
+	if len(f.sourceTypes) > 0 && !matchesSourceType(sourceType, f.sourceTypes) {
+		return false
+	}
+
+	if matchesSourceType(sourceType, f.excludeSourceTypes) {
+		return false
+	}
+
+	log := env.GetLog()
+	if log == nil {
+		return true
+	}
+
+	if len(f.include) > 0 && !matchesAny(log.Payload, f.include) {
+		return false
+	}
+
+	return !matchesAny(log.Payload, f.exclude)
+}
+
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, e := range exprs {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, re)
+	}
+
+	return res, nil
+}
+
+func matchesAny(payload []byte, res []*regexp.Regexp) bool {
+	for _, re := range res {
+		if re.Match(payload) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesSourceType(sourceType string, filters []string) bool {
+	sourceType = strings.ToUpper(sourceType)
+	for _, f := range filters {
+		f = strings.ToUpper(f)
+		if sourceType == f || strings.HasPrefix(sourceType, f+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -61,6 +61,14 @@ func WithMetricsToSyslogEnabled(enabled bool) SubscriberOption {
 	}
 }
 
+// WithLoadMeter returns a SubscriberOption that counts the envelopes
+// received for each binding.
+func WithLoadMeter(m *egress.LoadMeter) SubscriberOption {
+	return func(s *Subscriber) {
+		s.loadMeter = m
+	}
+}
+
 // Subscriber streams loggregator egress to the syslog drain.
 type Subscriber struct {
 	ctx                    context.Context
@@ -71,6 +79,7 @@ type Subscriber struct {
 	streamOpenTimeout      time.Duration
 	sourceIndex            string
 	metricsToSyslogEnabled bool
+	loadMeter              *egress.LoadMeter
 }
 
 type MetricClient interface {
@@ -124,21 +133,27 @@ func (s *Subscriber) Start(binding *v1.Binding) func() {
 		s.emitErrorLog(binding.AppId, "Invalid drain-type")
 	}
 
-	go s.connectAndRead(ctx, binding, selectors)
+	filter, err := newEnvelopeFilter(url.Query())
+	if err != nil {
+		s.emitErrorLog(binding.AppId, "Invalid drain filter: "+err.Error())
+		return cancel
+	}
+
+	go s.connectAndRead(ctx, binding, selectors, filter)
 
 	return cancel
 }
 
-func (s *Subscriber) connectAndRead(ctx context.Context, binding *v1.Binding, selectors []*v2.Selector) {
+func (s *Subscriber) connectAndRead(ctx context.Context, binding *v1.Binding, selectors []*v2.Selector, filter *envelopeFilter) {
 	for !isDone(ctx) {
-		cont := s.attemptConnectAndRead(ctx, binding, selectors)
+		cont := s.attemptConnectAndRead(ctx, binding, selectors, filter)
 		if !cont {
 			return
 		}
 	}
 }
 
-func (s *Subscriber) attemptConnectAndRead(ctx context.Context, binding *v1.Binding, selectors []*v2.Selector) bool {
+func (s *Subscriber) attemptConnectAndRead(ctx context.Context, binding *v1.Binding, selectors []*v2.Selector, filter *envelopeFilter) bool {
 	var cancel func()
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
@@ -181,7 +196,11 @@ func (s *Subscriber) attemptConnectAndRead(ctx context.Context, binding *v1.Bind
 	}
 	defer batchReceiver.CloseSend()
 
-	if err := s.batchReadWriteLoop(binding.AppId, batchReceiver, writer); err != nil {
+	counter := s.loadMeter.Counter(binding)
+	counter.SetStreaming(true)
+	defer counter.SetStreaming(false)
+
+	if err := s.batchReadWriteLoop(binding.AppId, batchReceiver, writer, filter, counter); err != nil {
 		loopStatus, ok := status.FromError(err)
 		if ok && loopStatus.Code() == codes.ResourceExhausted {
 			time.Sleep(20 * time.Millisecond)
@@ -222,7 +241,7 @@ func (s *Subscriber) readWriteLoop(sourceId string, r v2.Egress_ReceiverClient,
 	}
 }
 
-func (s *Subscriber) batchReadWriteLoop(sourceId string, r v2.Egress_BatchedReceiverClient, w egress.Writer) error {
+func (s *Subscriber) batchReadWriteLoop(sourceId string, r v2.Egress_BatchedReceiverClient, w egress.Writer, filter *envelopeFilter, counter *egress.LoadCounter) error {
 	for {
 		envBatch, err := r.Recv()
 		if err != nil {
@@ -230,6 +249,7 @@ func (s *Subscriber) batchReadWriteLoop(sourceId string, r v2.Egress_BatchedRece
 		}
 
 		s.ingressMetric.Increment(uint64(len(envBatch.Batch)))
+		counter.AddIngress(len(envBatch.Batch))
 
 		for _, env := range envBatch.Batch {
 
@@ -241,6 +261,11 @@ func (s *Subscriber) batchReadWriteLoop(sourceId string, r v2.Egress_BatchedRece
 				log.Print("Warning! Logs provider gave us an unexpected app-id!")
 				continue
 			}
+
+			if !filter.allowed(env) {
+				continue
+			}
+
 			// We decided to ignore the error from the writer since in most
 			// situations the connector will provide a diode writer and the diode
 			// writer never returns an error.
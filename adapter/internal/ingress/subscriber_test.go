@@ -18,6 +18,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -244,6 +245,73 @@ var _ = Describe("Subscriber", func() {
 		Consistently(writer.writes).Should(BeZero())
 	})
 
+	Describe("filter options", func() {
+		var subscriber *ingress.Subscriber
+
+		BeforeEach(func() {
+			batchedReceiverClient.recv = &v2.EnvelopeBatch{
+				Batch: []*v2.Envelope{
+					buildFilterEnvelope("APP/PROC/WEB", "GET /health 200"),
+					buildFilterEnvelope("APP/PROC/WEB", "ERROR something broke"),
+					buildFilterEnvelope("RTR", "GET /health 200"),
+					buildFilterEnvelope("STG", "Staging complete"),
+				},
+			}
+			client.batchedReceiverClient = batchedReceiverClient
+			subscriber = ingress.NewSubscriber(
+				context.TODO(),
+				clientPool,
+				syslogConnector,
+				spyEmitter,
+				ingress.WithStreamOpenTimeout(500*time.Millisecond),
+				ingress.WithLogClient(logClient, "some-source-index"),
+			)
+		})
+
+		DescribeTable("writes only matching envelopes", func(query string, expected int) {
+			binding.Drain = "https://some-drain?" + query
+			subscriber.Start(binding)
+
+			Eventually(writer.writes).Should(Equal(expected))
+			Consistently(writer.writes).Should(Equal(expected))
+		},
+			Entry("no filters", "", 4),
+			Entry("include", "include=health", 2),
+			Entry("multiple includes", "include=health&include=ERROR", 3),
+			Entry("exclude", "exclude=health", 2),
+			Entry("include and exclude", "include=GET&exclude=RTR|STG", 2),
+			Entry("source type prefix", "source-type=APP", 2),
+			Entry("exact source type", "source-type=APP/PROC/WEB", 2),
+			Entry("lowercase source type", "source-type=rtr", 1),
+			Entry("exclude source type", "exclude-source-type=RTR", 3),
+			Entry("source type and payload", "source-type=APP&exclude=health", 1),
+		)
+
+		It("does not apply payload filters to metrics", func() {
+			batchedReceiverClient.recv = &v2.EnvelopeBatch{
+				Batch: []*v2.Envelope{
+					buildGaugeEnvelope("some-app-id"),
+				},
+			}
+			binding.Drain = "https://some-drain?include=nothing-matches"
+			subscriber.Start(binding)
+
+			Eventually(writer.writes).Should(Equal(1))
+		})
+
+		It("emits a log and does not stream on an invalid filter", func() {
+			binding.Drain = "https://some-drain?include=("
+			subscriber.Start(binding)
+
+			Eventually(logClient.message).Should(ContainElement(
+				HavePrefix("Invalid drain filter"),
+			))
+			Expect(logClient.sourceType()).To(HaveKey("SYS"))
+			Consistently(client.batchedReceiverRequest).Should(BeNil())
+			Expect(writer.writes()).To(BeZero())
+		})
+	})
+
 	Describe("drain-type option", func() {
 		BeforeEach(func() {
 			batchedReceiverClient.recv = buildBatchedLogs(3)
@@ -471,6 +539,27 @@ func buildLogEnvelope(sourceID string) *v2.Envelope {
 	}
 }
 
+func buildFilterEnvelope(sourceType, payload string) *v2.Envelope {
+	env := buildLogEnvelope("some-app-id")
+	env.Tags["source_type"] = sourceType
+	env.GetLog().Payload = []byte(payload)
+
+	return env
+}
+
+func buildGaugeEnvelope(sourceID string) *v2.Envelope {
+	return &v2.Envelope{
+		Tags: map[string]string{
+			"source_type": "APP/PROC/WEB",
+		},
+		Timestamp: 12345678,
+		SourceId:  sourceID,
+		Message: &v2.Envelope_Gauge{
+			Gauge: &v2.Gauge{},
+		},
+	}
+}
+
 func buildCounterEnvelope() *v2.Envelope {
 	return &v2.Envelope{
 		Timestamp: 12345678,
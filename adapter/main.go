@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"log"
 	"net"
 	"os"
@@ -66,6 +67,18 @@ func main() {
 		pulseemitter.WithSourceID("drain_adapter"),
 	)
 
+	var syslogClientCert *tls.Certificate
+	if cfg.SyslogClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(
+			cfg.SyslogClientCertFile,
+			cfg.SyslogClientKeyFile,
+		)
+		if err != nil {
+			log.Fatalf("Invalid syslog client certificate: %s", err)
+		}
+		syslogClientCert = &cert
+	}
+
 	go startPprof(cfg.PprofHostport)
 
 	adapter := app.NewAdapter(
@@ -81,9 +94,31 @@ func main() {
 		app.WithSyslogKeepalive(cfg.SyslogKeepalive),
 		app.WithSyslogDialTimeout(cfg.SyslogDialTimeout),
 		app.WithSyslogIOTimeout(cfg.SyslogIOTimeout),
+		app.WithSyslogFailbackInterval(cfg.SyslogFailbackInterval),
+		app.WithSyslogWriteBuffer(
+			cfg.SyslogWriteBufferSize,
+			cfg.SyslogWriteLinger,
+		),
+		app.WithSyslogRateLimit(
+			cfg.SyslogRateLimit,
+			cfg.SyslogRateLimitBytes,
+			cfg.SyslogRateLimitBurst,
+		),
+		app.WithSyslogAppRateLimit(
+			cfg.SyslogAppRateLimit,
+			cfg.SyslogAppRateBytes,
+		),
+		app.WithSyslogHTTPSMaxConnsPerHost(cfg.SyslogHTTPSMaxConns),
+		app.WithSyslogRedaction(cfg.SyslogRedaction),
 		app.WithSyslogSkipCertVerify(cfg.SyslogSkipCertVerify),
+		app.WithSyslogClientCert(syslogClientCert),
+		app.WithSyslogTLSPolicy(cfg.SyslogTLSPolicy),
+		app.WithSyslogProxy(cfg.SyslogProxies),
+		app.WithSyslogMessageIDs(cfg.SyslogMessageIDs),
 		app.WithMetricsToSyslogEnabled(cfg.MetricsToSyslogEnabled),
 		app.WithMaxBindings(cfg.MaxBindings),
+		app.WithAvailabilityZone(cfg.AvailabilityZone),
+		app.WithDrainTimeout(cfg.DrainTimeout),
 	)
 	go adapter.Start()
 	defer adapter.Stop()
@@ -16,6 +16,13 @@ It has these top-level messages:
 	CreateBindingResponse
 	DeleteBindingRequest
 	DeleteBindingResponse
+	GetLoadRequest
+	GetLoadResponse
+	BindingLoad
+	AcquireLeaseRequest
+	AcquireLeaseResponse
+	ReleaseLeaseRequest
+	ReleaseLeaseResponse
 */
 package scalablesyslog
 
@@ -43,6 +50,20 @@ type Binding struct {
 	AppId    string `protobuf:"bytes,1,opt,name=appId" json:"appId,omitempty"`
 	Hostname string `protobuf:"bytes,2,opt,name=hostname" json:"hostname,omitempty"`
 	Drain    string `protobuf:"bytes,3,opt,name=drain" json:"drain,omitempty"`
+	// clientCert and clientKey are the PEM encoded certificate and key
+	// presented to drains that require mutual TLS.
+	ClientCert string `protobuf:"bytes,4,opt,name=clientCert" json:"clientCert,omitempty"`
+	ClientKey  string `protobuf:"bytes,5,opt,name=clientKey" json:"clientKey,omitempty"`
+	// caCert is a PEM encoded CA bundle the drain certificate is verified
+	// against. spkiPins is a comma separated list of base64 encoded SHA-256
+	// hashes of the subject public key info, one of which the drain
+	// certificate has to match.
+	CaCert   string `protobuf:"bytes,6,opt,name=caCert" json:"caCert,omitempty"`
+	SpkiPins string `protobuf:"bytes,7,opt,name=spkiPins" json:"spkiPins,omitempty"`
+	// credentialsDigest is a hex encoded SHA-256 digest of the credentials
+	// above. Adapters report their bindings with the digest in place of the
+	// credentials so that the key material never leaves the adapter.
+	CredentialsDigest string `protobuf:"bytes,8,opt,name=credentialsDigest" json:"credentialsDigest,omitempty"`
 }
 
 func (m *Binding) Reset()                    { *m = Binding{} }
@@ -71,6 +92,41 @@ func (m *Binding) GetDrain() string {
 	return ""
 }
 
+func (m *Binding) GetClientCert() string {
+	if m != nil {
+		return m.ClientCert
+	}
+	return ""
+}
+
+func (m *Binding) GetClientKey() string {
+	if m != nil {
+		return m.ClientKey
+	}
+	return ""
+}
+
+func (m *Binding) GetCaCert() string {
+	if m != nil {
+		return m.CaCert
+	}
+	return ""
+}
+
+func (m *Binding) GetSpkiPins() string {
+	if m != nil {
+		return m.SpkiPins
+	}
+	return ""
+}
+
+func (m *Binding) GetCredentialsDigest() string {
+	if m != nil {
+		return m.CredentialsDigest
+	}
+	return ""
+}
+
 type ListBindingsRequest struct {
 }
 
@@ -143,6 +199,210 @@ func (m *DeleteBindingResponse) String() string            { return proto.Compac
 func (*DeleteBindingResponse) ProtoMessage()               {}
 func (*DeleteBindingResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
+type GetLoadRequest struct {
+}
+
+func (m *GetLoadRequest) Reset()                    { *m = GetLoadRequest{} }
+func (m *GetLoadRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetLoadRequest) ProtoMessage()               {}
+func (*GetLoadRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+// GetLoadResponse reports how busy an adapter is. Rates are in envelopes per
+// second. bufferFill is the average fill of the binding buffers between 0
+// and 1. maxBindings is the number of bindings the adapter accepts. zone is
+// the availability zone of the adapter. draining is set while the adapter is
+// shutting down and waits for its bindings to be moved.
+type GetLoadResponse struct {
+	IngressRate float64        `protobuf:"fixed64,1,opt,name=ingressRate" json:"ingressRate,omitempty"`
+	EgressRate  float64        `protobuf:"fixed64,2,opt,name=egressRate" json:"egressRate,omitempty"`
+	BufferFill  float64        `protobuf:"fixed64,3,opt,name=bufferFill" json:"bufferFill,omitempty"`
+	Connections int64          `protobuf:"varint,4,opt,name=connections" json:"connections,omitempty"`
+	MaxBindings int64          `protobuf:"varint,5,opt,name=maxBindings" json:"maxBindings,omitempty"`
+	Bindings    []*BindingLoad `protobuf:"bytes,6,rep,name=bindings" json:"bindings,omitempty"`
+	Zone        string         `protobuf:"bytes,7,opt,name=zone" json:"zone,omitempty"`
+	Draining    bool           `protobuf:"varint,8,opt,name=draining" json:"draining,omitempty"`
+}
+
+func (m *GetLoadResponse) Reset()                    { *m = GetLoadResponse{} }
+func (m *GetLoadResponse) String() string            { return proto.CompactTextString(m) }
+func (*GetLoadResponse) ProtoMessage()               {}
+func (*GetLoadResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *GetLoadResponse) GetIngressRate() float64 {
+	if m != nil {
+		return m.IngressRate
+	}
+	return 0
+}
+
+func (m *GetLoadResponse) GetEgressRate() float64 {
+	if m != nil {
+		return m.EgressRate
+	}
+	return 0
+}
+
+func (m *GetLoadResponse) GetBufferFill() float64 {
+	if m != nil {
+		return m.BufferFill
+	}
+	return 0
+}
+
+func (m *GetLoadResponse) GetConnections() int64 {
+	if m != nil {
+		return m.Connections
+	}
+	return 0
+}
+
+func (m *GetLoadResponse) GetMaxBindings() int64 {
+	if m != nil {
+		return m.MaxBindings
+	}
+	return 0
+}
+
+func (m *GetLoadResponse) GetBindings() []*BindingLoad {
+	if m != nil {
+		return m.Bindings
+	}
+	return nil
+}
+
+func (m *GetLoadResponse) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+func (m *GetLoadResponse) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+// BindingLoad is the observed volume of a single binding. streaming is set
+// while the adapter has an open log stream for the binding.
+type BindingLoad struct {
+	Binding     *Binding `protobuf:"bytes,1,opt,name=binding" json:"binding,omitempty"`
+	IngressRate float64  `protobuf:"fixed64,2,opt,name=ingressRate" json:"ingressRate,omitempty"`
+	EgressRate  float64  `protobuf:"fixed64,3,opt,name=egressRate" json:"egressRate,omitempty"`
+	BufferFill  float64  `protobuf:"fixed64,4,opt,name=bufferFill" json:"bufferFill,omitempty"`
+	Streaming   bool     `protobuf:"varint,5,opt,name=streaming" json:"streaming,omitempty"`
+}
+
+func (m *BindingLoad) Reset()                    { *m = BindingLoad{} }
+func (m *BindingLoad) String() string            { return proto.CompactTextString(m) }
+func (*BindingLoad) ProtoMessage()               {}
+func (*BindingLoad) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *BindingLoad) GetBinding() *Binding {
+	if m != nil {
+		return m.Binding
+	}
+	return nil
+}
+
+func (m *BindingLoad) GetIngressRate() float64 {
+	if m != nil {
+		return m.IngressRate
+	}
+	return 0
+}
+
+func (m *BindingLoad) GetEgressRate() float64 {
+	if m != nil {
+		return m.EgressRate
+	}
+	return 0
+}
+
+func (m *BindingLoad) GetBufferFill() float64 {
+	if m != nil {
+		return m.BufferFill
+	}
+	return 0
+}
+
+func (m *BindingLoad) GetStreaming() bool {
+	if m != nil {
+		return m.Streaming
+	}
+	return false
+}
+
+// AcquireLeaseRequest asks the adapter for the leader lease of the
+// schedulers. The holder is granted the lease for ttlMillis milliseconds if
+// no other holder owns a lease that has not expired. A holder renews its
+// lease by acquiring it again.
+type AcquireLeaseRequest struct {
+	Holder    string `protobuf:"bytes,1,opt,name=holder" json:"holder,omitempty"`
+	TtlMillis int64  `protobuf:"varint,2,opt,name=ttlMillis" json:"ttlMillis,omitempty"`
+}
+
+func (m *AcquireLeaseRequest) Reset()                    { *m = AcquireLeaseRequest{} }
+func (m *AcquireLeaseRequest) String() string            { return proto.CompactTextString(m) }
+func (*AcquireLeaseRequest) ProtoMessage()               {}
+func (*AcquireLeaseRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *AcquireLeaseRequest) GetHolder() string {
+	if m != nil {
+		return m.Holder
+	}
+	return ""
+}
+
+func (m *AcquireLeaseRequest) GetTtlMillis() int64 {
+	if m != nil {
+		return m.TtlMillis
+	}
+	return 0
+}
+
+type AcquireLeaseResponse struct {
+	Acquired bool `protobuf:"varint,1,opt,name=acquired" json:"acquired,omitempty"`
+}
+
+func (m *AcquireLeaseResponse) Reset()                    { *m = AcquireLeaseResponse{} }
+func (m *AcquireLeaseResponse) String() string            { return proto.CompactTextString(m) }
+func (*AcquireLeaseResponse) ProtoMessage()               {}
+func (*AcquireLeaseResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *AcquireLeaseResponse) GetAcquired() bool {
+	if m != nil {
+		return m.Acquired
+	}
+	return false
+}
+
+// ReleaseLeaseRequest gives up the lease if the holder owns it.
+type ReleaseLeaseRequest struct {
+	Holder string `protobuf:"bytes,1,opt,name=holder" json:"holder,omitempty"`
+}
+
+func (m *ReleaseLeaseRequest) Reset()                    { *m = ReleaseLeaseRequest{} }
+func (m *ReleaseLeaseRequest) String() string            { return proto.CompactTextString(m) }
+func (*ReleaseLeaseRequest) ProtoMessage()               {}
+func (*ReleaseLeaseRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *ReleaseLeaseRequest) GetHolder() string {
+	if m != nil {
+		return m.Holder
+	}
+	return ""
+}
+
+type ReleaseLeaseResponse struct {
+}
+
+func (m *ReleaseLeaseResponse) Reset()                    { *m = ReleaseLeaseResponse{} }
+func (m *ReleaseLeaseResponse) String() string            { return proto.CompactTextString(m) }
+func (*ReleaseLeaseResponse) ProtoMessage()               {}
+func (*ReleaseLeaseResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
 func init() {
 	proto.RegisterType((*Binding)(nil), "scalablesyslog.Binding")
 	proto.RegisterType((*ListBindingsRequest)(nil), "scalablesyslog.ListBindingsRequest")
@@ -151,6 +411,13 @@ func init() {
 	proto.RegisterType((*CreateBindingResponse)(nil), "scalablesyslog.CreateBindingResponse")
 	proto.RegisterType((*DeleteBindingRequest)(nil), "scalablesyslog.DeleteBindingRequest")
 	proto.RegisterType((*DeleteBindingResponse)(nil), "scalablesyslog.DeleteBindingResponse")
+	proto.RegisterType((*GetLoadRequest)(nil), "scalablesyslog.GetLoadRequest")
+	proto.RegisterType((*GetLoadResponse)(nil), "scalablesyslog.GetLoadResponse")
+	proto.RegisterType((*BindingLoad)(nil), "scalablesyslog.BindingLoad")
+	proto.RegisterType((*AcquireLeaseRequest)(nil), "scalablesyslog.AcquireLeaseRequest")
+	proto.RegisterType((*AcquireLeaseResponse)(nil), "scalablesyslog.AcquireLeaseResponse")
+	proto.RegisterType((*ReleaseLeaseRequest)(nil), "scalablesyslog.ReleaseLeaseRequest")
+	proto.RegisterType((*ReleaseLeaseResponse)(nil), "scalablesyslog.ReleaseLeaseResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -167,6 +434,9 @@ type AdapterClient interface {
 	ListBindings(ctx context.Context, in *ListBindingsRequest, opts ...grpc.CallOption) (*ListBindingsResponse, error)
 	CreateBinding(ctx context.Context, in *CreateBindingRequest, opts ...grpc.CallOption) (*CreateBindingResponse, error)
 	DeleteBinding(ctx context.Context, in *DeleteBindingRequest, opts ...grpc.CallOption) (*DeleteBindingResponse, error)
+	GetLoad(ctx context.Context, in *GetLoadRequest, opts ...grpc.CallOption) (*GetLoadResponse, error)
+	AcquireLease(ctx context.Context, in *AcquireLeaseRequest, opts ...grpc.CallOption) (*AcquireLeaseResponse, error)
+	ReleaseLease(ctx context.Context, in *ReleaseLeaseRequest, opts ...grpc.CallOption) (*ReleaseLeaseResponse, error)
 }
 
 type adapterClient struct {
@@ -204,12 +474,42 @@ func (c *adapterClient) DeleteBinding(ctx context.Context, in *DeleteBindingRequ
 	return out, nil
 }
 
+func (c *adapterClient) GetLoad(ctx context.Context, in *GetLoadRequest, opts ...grpc.CallOption) (*GetLoadResponse, error) {
+	out := new(GetLoadResponse)
+	err := grpc.Invoke(ctx, "/scalablesyslog.Adapter/GetLoad", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adapterClient) AcquireLease(ctx context.Context, in *AcquireLeaseRequest, opts ...grpc.CallOption) (*AcquireLeaseResponse, error) {
+	out := new(AcquireLeaseResponse)
+	err := grpc.Invoke(ctx, "/scalablesyslog.Adapter/AcquireLease", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adapterClient) ReleaseLease(ctx context.Context, in *ReleaseLeaseRequest, opts ...grpc.CallOption) (*ReleaseLeaseResponse, error) {
+	out := new(ReleaseLeaseResponse)
+	err := grpc.Invoke(ctx, "/scalablesyslog.Adapter/ReleaseLease", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Adapter service
 
 type AdapterServer interface {
 	ListBindings(context.Context, *ListBindingsRequest) (*ListBindingsResponse, error)
 	CreateBinding(context.Context, *CreateBindingRequest) (*CreateBindingResponse, error)
 	DeleteBinding(context.Context, *DeleteBindingRequest) (*DeleteBindingResponse, error)
+	GetLoad(context.Context, *GetLoadRequest) (*GetLoadResponse, error)
+	AcquireLease(context.Context, *AcquireLeaseRequest) (*AcquireLeaseResponse, error)
+	ReleaseLease(context.Context, *ReleaseLeaseRequest) (*ReleaseLeaseResponse, error)
 }
 
 func RegisterAdapterServer(s *grpc.Server, srv AdapterServer) {
@@ -270,6 +570,60 @@ func _Adapter_DeleteBinding_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Adapter_GetLoad_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterServer).GetLoad(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scalablesyslog.Adapter/GetLoad",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterServer).GetLoad(ctx, req.(*GetLoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Adapter_AcquireLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterServer).AcquireLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scalablesyslog.Adapter/AcquireLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterServer).AcquireLease(ctx, req.(*AcquireLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Adapter_ReleaseLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdapterServer).ReleaseLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/scalablesyslog.Adapter/ReleaseLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdapterServer).ReleaseLease(ctx, req.(*ReleaseLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Adapter_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "scalablesyslog.Adapter",
 	HandlerType: (*AdapterServer)(nil),
@@ -286,6 +640,18 @@ var _Adapter_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteBinding",
 			Handler:    _Adapter_DeleteBinding_Handler,
 		},
+		{
+			MethodName: "GetLoad",
+			Handler:    _Adapter_GetLoad_Handler,
+		},
+		{
+			MethodName: "AcquireLease",
+			Handler:    _Adapter_AcquireLease_Handler,
+		},
+		{
+			MethodName: "ReleaseLease",
+			Handler:    _Adapter_ReleaseLease_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "adapter.proto",
@@ -294,23 +660,45 @@ var _Adapter_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("adapter.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 284 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x92, 0xbd, 0x4e, 0xc3, 0x30,
-	0x14, 0x85, 0x9b, 0x56, 0x90, 0x72, 0xa1, 0x0c, 0x26, 0x55, 0xa3, 0x4c, 0x95, 0x01, 0xa9, 0x53,
-	0x24, 0xda, 0x27, 0xe0, 0x67, 0xa9, 0x60, 0x21, 0x2b, 0x12, 0x92, 0x43, 0xae, 0x4a, 0xa4, 0x60,
-	0x9b, 0x5c, 0x33, 0xf0, 0x42, 0x3c, 0x27, 0x6a, 0xec, 0x56, 0xcd, 0x8f, 0xc2, 0xc2, 0x78, 0xed,
-	0x73, 0xbf, 0xe3, 0x73, 0x12, 0x98, 0x88, 0x4c, 0x68, 0x83, 0x65, 0xac, 0x4b, 0x65, 0x14, 0x3b,
-	0xa7, 0x37, 0x51, 0x88, 0xb4, 0x40, 0xfa, 0xa6, 0x42, 0x6d, 0xf8, 0x33, 0xf8, 0x77, 0xb9, 0xcc,
-	0x72, 0xb9, 0x61, 0x01, 0x1c, 0x09, 0xad, 0xd7, 0x59, 0xe8, 0xcd, 0xbd, 0xc5, 0x49, 0x62, 0x07,
-	0x16, 0xc1, 0xf8, 0x5d, 0x91, 0x91, 0xe2, 0x03, 0xc3, 0x61, 0x75, 0xb1, 0x9f, 0xb7, 0x1b, 0x59,
-	0x29, 0x72, 0x19, 0x8e, 0xec, 0x46, 0x35, 0xf0, 0x29, 0x5c, 0x3c, 0xe5, 0x64, 0x1c, 0x96, 0x12,
-	0xfc, 0xfc, 0x42, 0x32, 0xfc, 0x11, 0x82, 0xfa, 0x31, 0x69, 0x25, 0x09, 0xd9, 0x0a, 0xc6, 0xa9,
-	0x3b, 0x0b, 0xbd, 0xf9, 0x68, 0x71, 0xba, 0x9c, 0xc5, 0xf5, 0x47, 0xc6, 0x6e, 0x27, 0xd9, 0x0b,
-	0xf9, 0x1a, 0x82, 0xfb, 0x12, 0x85, 0xc1, 0xdd, 0x95, 0x35, 0x61, 0x37, 0xe0, 0x3b, 0x4d, 0x95,
-	0xa2, 0x87, 0xb5, 0xd3, 0xf1, 0x19, 0x4c, 0x1b, 0x28, 0xfb, 0xb0, 0xad, 0xc7, 0x03, 0x16, 0xf8,
-	0x4f, 0x1e, 0x0d, 0x94, 0xf5, 0x58, 0xfe, 0x0c, 0xc1, 0xbf, 0xb5, 0x1f, 0x88, 0xbd, 0xc0, 0xd9,
-	0x61, 0x41, 0xec, 0xb2, 0x89, 0xed, 0x68, 0x35, 0xba, 0xea, 0x17, 0xb9, 0x28, 0x03, 0xf6, 0x0a,
-	0x93, 0x5a, 0x4a, 0xd6, 0x5a, 0xec, 0xea, 0x33, 0xba, 0xfe, 0x43, 0x75, 0xc8, 0xaf, 0x25, 0x6c,
-	0xf3, 0xbb, 0xba, 0x6c, 0xf3, 0x3b, 0x6b, 0xe2, 0x83, 0xf4, 0xb8, 0xfa, 0x7d, 0x57, 0xbf, 0x01,
-	0x00, 0x00, 0xff, 0xff, 0xba, 0x87, 0x9a, 0x05, 0xcf, 0x02, 0x00, 0x00,
+	// 637 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0xdd, 0x4e, 0x13, 0x41,
+	0x14, 0x76, 0xdb, 0xd2, 0x96, 0xc3, 0x8f, 0x3a, 0x14, 0xd8, 0xac, 0x04, 0xc9, 0x0a, 0x09, 0x17,
+	0x4a, 0x22, 0x5c, 0x78, 0x8d, 0x10, 0x0d, 0xa1, 0x26, 0x66, 0x6e, 0x49, 0x4c, 0xa6, 0xbb, 0x87,
+	0x32, 0x71, 0x98, 0x2d, 0x3b, 0x43, 0x22, 0x3e, 0x8d, 0x4f, 0xe0, 0x33, 0xf8, 0x4a, 0xbe, 0x81,
+	0x99, 0xd9, 0xd9, 0xed, 0x6c, 0x5b, 0x5a, 0x63, 0xbc, 0xeb, 0xf9, 0xce, 0x37, 0xdf, 0x99, 0x3d,
+	0xe7, 0x9b, 0x53, 0x58, 0x63, 0x29, 0x1b, 0x69, 0xcc, 0x8f, 0x46, 0x79, 0xa6, 0x33, 0xb2, 0xae,
+	0x12, 0x26, 0xd8, 0x40, 0xa0, 0x7a, 0x50, 0x22, 0x1b, 0xc6, 0xbf, 0x03, 0xe8, 0xbc, 0xe7, 0x32,
+	0xe5, 0x72, 0x48, 0x7a, 0xb0, 0xc4, 0x46, 0xa3, 0x8b, 0x34, 0x0c, 0xf6, 0x82, 0xc3, 0x65, 0x5a,
+	0x04, 0x24, 0x82, 0xee, 0x4d, 0xa6, 0xb4, 0x64, 0xb7, 0x18, 0x36, 0x6c, 0xa2, 0x8a, 0xcd, 0x89,
+	0x34, 0x67, 0x5c, 0x86, 0xcd, 0xe2, 0x84, 0x0d, 0xc8, 0x2e, 0x40, 0x22, 0x38, 0x4a, 0x7d, 0x86,
+	0xb9, 0x0e, 0x5b, 0x36, 0xe5, 0x21, 0x64, 0x07, 0x96, 0x8b, 0xe8, 0x12, 0x1f, 0xc2, 0x25, 0x9b,
+	0x1e, 0x03, 0x64, 0x0b, 0xda, 0x09, 0xb3, 0x27, 0xdb, 0x36, 0xe5, 0x22, 0x73, 0x0f, 0x35, 0xfa,
+	0xca, 0x3f, 0x73, 0xa9, 0xc2, 0x4e, 0x71, 0x8f, 0x32, 0x26, 0xaf, 0xe1, 0x79, 0x92, 0x63, 0x8a,
+	0x52, 0x73, 0x26, 0xd4, 0x39, 0x1f, 0xa2, 0xd2, 0x61, 0xd7, 0x92, 0xa6, 0x13, 0xf1, 0x26, 0x6c,
+	0xf4, 0xb9, 0xd2, 0xee, 0xb3, 0x15, 0xc5, 0xbb, 0x7b, 0x03, 0x5f, 0x42, 0xaf, 0x0e, 0xab, 0x51,
+	0x26, 0x15, 0x92, 0x13, 0xe8, 0x0e, 0x1c, 0x16, 0x06, 0x7b, 0xcd, 0xc3, 0x95, 0xe3, 0xed, 0xa3,
+	0x7a, 0x17, 0x8f, 0xdc, 0x19, 0x5a, 0x11, 0xe3, 0x0b, 0xe8, 0x9d, 0xe5, 0xc8, 0x34, 0x96, 0xa9,
+	0xa2, 0x08, 0x79, 0x0b, 0x1d, 0xc7, 0xb1, 0x5d, 0x9e, 0xa3, 0x55, 0xf2, 0xe2, 0x6d, 0xd8, 0x9c,
+	0x90, 0x2a, 0x2e, 0x66, 0x6a, 0x9c, 0xa3, 0xc0, 0xff, 0x54, 0x63, 0x42, 0xca, 0xd5, 0x78, 0x06,
+	0xeb, 0x1f, 0x51, 0xf7, 0x33, 0x96, 0x96, 0x6d, 0xfa, 0xd1, 0x80, 0xa7, 0x15, 0xe4, 0x5a, 0xb4,
+	0x07, 0x2b, 0x5c, 0x0e, 0x73, 0x54, 0x8a, 0x32, 0x8d, 0xb6, 0x6a, 0x40, 0x7d, 0xc8, 0x78, 0x02,
+	0xc7, 0x84, 0x86, 0x25, 0x00, 0xd6, 0xf2, 0x83, 0xfb, 0xeb, 0x6b, 0xcc, 0x3f, 0x70, 0x21, 0xac,
+	0x9d, 0x02, 0xea, 0x21, 0xa6, 0x42, 0x92, 0x49, 0x89, 0x89, 0xe6, 0x99, 0x54, 0xd6, 0x54, 0x4d,
+	0xea, 0x43, 0x86, 0x71, 0xcb, 0xbe, 0x95, 0xd3, 0xb3, 0xbe, 0x6a, 0x52, 0x1f, 0x22, 0xef, 0xbc,
+	0x41, 0xb6, 0xed, 0x20, 0x5f, 0x3c, 0xd2, 0x18, 0xfb, 0x71, 0x15, 0x99, 0x10, 0x68, 0x7d, 0xcf,
+	0x24, 0x3a, 0xdb, 0xd9, 0xdf, 0xc6, 0x8e, 0xd6, 0xed, 0xa6, 0xcb, 0xc6, 0x69, 0x5d, 0x5a, 0xc5,
+	0xf1, 0xaf, 0x00, 0x56, 0x3c, 0xa5, 0x7f, 0x18, 0xc8, 0x64, 0x47, 0x1b, 0x8b, 0x3a, 0xda, 0x5c,
+	0xd0, 0xd1, 0xd6, 0x54, 0x47, 0x77, 0x60, 0x59, 0xe9, 0x1c, 0xd9, 0xad, 0xb9, 0xd6, 0x92, 0xfd,
+	0x82, 0x31, 0x10, 0x5f, 0xc2, 0xc6, 0x69, 0x72, 0x77, 0xcf, 0x73, 0xec, 0x23, 0x53, 0x58, 0x5a,
+	0x6b, 0x0b, 0xda, 0x37, 0x99, 0x48, 0x31, 0x77, 0x3b, 0xc2, 0x45, 0x46, 0x4c, 0x6b, 0xf1, 0x89,
+	0x0b, 0xc1, 0x95, 0xbd, 0x6c, 0x93, 0x8e, 0x81, 0xf8, 0x18, 0x7a, 0x75, 0x31, 0x67, 0x9b, 0x08,
+	0xba, 0xac, 0xc0, 0x8b, 0x9d, 0xd3, 0xa5, 0x55, 0x1c, 0xbf, 0x81, 0x0d, 0x8a, 0xc2, 0xd0, 0xff,
+	0xe6, 0x02, 0xf1, 0x16, 0xf4, 0xea, 0xf4, 0xa2, 0xc4, 0xf1, 0xcf, 0x16, 0x74, 0x4e, 0x8b, 0x0d,
+	0x48, 0xae, 0x60, 0xd5, 0x7f, 0xe0, 0xe4, 0xd5, 0xe4, 0x14, 0x66, 0x6c, 0x85, 0x68, 0x7f, 0x3e,
+	0xc9, 0x3d, 0x93, 0x27, 0xe4, 0x0b, 0xac, 0xd5, 0x5e, 0x29, 0x99, 0x3a, 0x38, 0x6b, 0x1f, 0x44,
+	0x07, 0x0b, 0x58, 0xbe, 0x7e, 0xed, 0x85, 0x4e, 0xeb, 0xcf, 0xda, 0x05, 0xd1, 0xc1, 0x02, 0x56,
+	0xa5, 0xdf, 0x87, 0x8e, 0x7b, 0xd5, 0x64, 0x77, 0xf2, 0x4c, 0x7d, 0x03, 0x44, 0x2f, 0x1f, 0xcd,
+	0x57, 0x6a, 0x57, 0xb0, 0xea, 0x4f, 0x7c, 0xba, 0xd5, 0x33, 0xcc, 0x15, 0xed, 0xcf, 0x27, 0xf9,
+	0xe2, 0xfe, 0xac, 0xa7, 0xc5, 0x67, 0x18, 0x27, 0xda, 0x9f, 0x4f, 0x2a, 0xc5, 0x07, 0x6d, 0xfb,
+	0x3f, 0x79, 0xf2, 0x67, 0x00, 0xe9, 0x9f, 0x00, 0x68, 0x38, 0x07, 0x00, 0x00,
 }
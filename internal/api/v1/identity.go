@@ -0,0 +1,34 @@
+package scalablesyslog
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// Identity returns the binding with its credentials replaced by a digest of
+// them. Bindings that differ only in their credentials have different
+// identities, so the identity can be compared and used as a map key in place
+// of the binding without holding the key material. The identity of a binding
+// without credentials is the binding itself, and the identity of an identity
+// is the identity.
+func Identity(b Binding) Binding {
+	if b.ClientCert == "" && b.ClientKey == "" && b.CaCert == "" && b.SpkiPins == "" {
+		return b
+	}
+
+	h := sha256.New()
+	for _, f := range []string{b.ClientCert, b.ClientKey, b.CaCert, b.SpkiPins} {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(f)))
+		h.Write(n[:])
+		h.Write([]byte(f))
+	}
+
+	return Binding{
+		AppId:             b.AppId,
+		Hostname:          b.Hostname,
+		Drain:             b.Drain,
+		CredentialsDigest: hex.EncodeToString(h.Sum(nil)),
+	}
+}
@@ -12,6 +12,7 @@ import (
 // The Health handler will report the number of drains back to user.
 type Health struct {
 	counts map[string]int
+	values map[string]interface{}
 	mu     sync.RWMutex
 }
 
@@ -19,6 +20,7 @@ type Health struct {
 func NewHealth() *Health {
 	return &Health{
 		counts: make(map[string]int),
+		values: make(map[string]interface{}),
 	}
 }
 
@@ -27,7 +29,14 @@ func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
 	h.mu.RLock()
-	jsonCounts, err := json.Marshal(h.counts)
+	body := make(map[string]interface{}, len(h.counts)+len(h.values))
+	for k, v := range h.counts {
+		body[k] = v
+	}
+	for k, v := range h.values {
+		body[k] = v
+	}
+	jsonCounts, err := json.Marshal(body)
 	h.mu.RUnlock()
 
 	if err != nil {
@@ -44,3 +53,12 @@ func (h *Health) SetCounter(c map[string]int) {
 		h.counts[k] = v
 	}
 }
+
+// SetValue reports a value that is not a count. The value is marshaled to
+// JSON when the handler is served and must not be modified afterwards.
+func (h *Health) SetValue(name string, v interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.values[name] = v
+}
@@ -46,4 +46,12 @@ var _ = Describe("Health", func() {
 		handler.ServeHTTP(recorder, new(http.Request))
 		Expect(recorder.Body.Bytes()).To(MatchJSON(`{"aCounter": 1, "bCounter": 2, "cCounter": 100}`))
 	})
+
+	It("returns JSON body with values", func() {
+		handler.SetCounter(map[string]int{"drainCount": 5})
+		handler.SetValue("adapters", map[string]string{"some-addr": "healthy"})
+
+		handler.ServeHTTP(recorder, new(http.Request))
+		Expect(recorder.Body.Bytes()).To(MatchJSON(`{"drainCount": 5, "adapters": {"some-addr": "healthy"}}`))
+	})
 })
@@ -1,12 +1,12 @@
 package app
 
 import (
-	"fmt"
 	"log"
-	"net"
+	"os"
 	"time"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
 )
 
@@ -20,6 +20,7 @@ type Config struct {
 	APISkipCertVerify  bool          `env:"API_SKIP_CERT_VERIFY"`
 	APIPollingInterval time.Duration `env:"API_POLLING_INTERVAL"`
 	APIBatchSize       int           `env:"API_BATCH_SIZE"`
+	APIResyncInterval  time.Duration `env:"API_RESYNC_INTERVAL"`
 
 	CAFile            string `env:"CA_FILE_PATH,        required"`
 	CertFile          string `env:"CERT_FILE_PATH,      required"`
@@ -28,8 +29,46 @@ type Config struct {
 
 	Blacklist *ingress.BlacklistRanges `env:"BLACKLIST"`
 
-	AdapterPort  string   `env:"ADAPTER_PORT,  required"`
-	AdapterAddrs []string `env:"ADAPTER_ADDRS, required"`
+	AdapterPort string `env:"ADAPTER_PORT"`
+
+	// AdapterAddrs are the hosts of the adapters. A host may be followed by
+	// @ and the availability zone of the adapters it resolves to, e.g.
+	// adapter.z1.example.com@z1. The hosts are resolved again every term.
+	AdapterAddrs []string `env:"ADAPTER_ADDRS"`
+
+	// AdapterAddrsFile is a file that lists the hostports of the adapters
+	// instead of ADAPTER_ADDRS. The file is read again every term.
+	AdapterAddrsFile string `env:"ADAPTER_ADDRS_FILE"`
+
+	// AdapterZones maps adapter hostports to their zones.
+	AdapterZones map[string]string
+
+	// AdapterSource discovers the adapters from ADAPTER_ADDRS or
+	// ADAPTER_ADDRS_FILE.
+	AdapterSource egress.AdapterSource
+
+	// AdapterReplicas is the number of adapters each drain is written to.
+	AdapterReplicas int `env:"ADAPTER_REPLICAS"`
+
+	// AdapterUnhealthyThreshold is the number of consecutive failed health
+	// checks after which an adapter receives no bindings.
+	AdapterUnhealthyThreshold int `env:"ADAPTER_UNHEALTHY_THRESHOLD"`
+
+	// RebalanceMaxMoves is the number of bindings that are moved per term
+	// to even out the load of the adapters. Adapters within
+	// RebalanceTolerancePercent of the average load are left alone.
+	// Rebalancing is disabled unless RebalanceMaxMoves is set.
+	RebalanceMaxMoves         int `env:"REBALANCE_MAX_MOVES"`
+	RebalanceTolerancePercent int `env:"REBALANCE_TOLERANCE_PERCENT"`
+
+	// LeaderLockFile enables leader election between the schedulers that
+	// share the file. LeaderLockAdapters enables leader election with a
+	// lease held by the adapters instead, which works between schedulers on
+	// different VMs. LeaderID defaults to the hostname.
+	LeaderLockFile     string        `env:"LEADER_LOCK_FILE"`
+	LeaderLockAdapters bool          `env:"LEADER_LOCK_ADAPTERS"`
+	LeaderID           string        `env:"LEADER_ID"`
+	LeaderLeaseTTL     time.Duration `env:"LEADER_LEASE_TTL"`
 
 	MetricIngressAddr     string        `env:"METRIC_INGRESS_ADDR, required"`
 	MetricIngressCN       string        `env:"METRIC_INGRESS_CN,   required"`
@@ -43,43 +82,51 @@ type Config struct {
 // status code 1.
 func LoadConfig(args []string) (*Config, error) {
 	cfg := Config{
-		HealthHostport:        ":8080",
-		PprofHostport:         "localhost:6060",
-		APISkipCertVerify:     false,
-		APIPollingInterval:    15 * time.Second,
-		MetricEmitterInterval: time.Minute,
-		Blacklist:             &ingress.BlacklistRanges{},
-		APIBatchSize:          1000,
+		HealthHostport:            ":8080",
+		PprofHostport:             "localhost:6060",
+		APISkipCertVerify:         false,
+		APIPollingInterval:        15 * time.Second,
+		MetricEmitterInterval:     time.Minute,
+		Blacklist:                 &ingress.BlacklistRanges{},
+		APIBatchSize:              1000,
+		APIResyncInterval:         10 * time.Minute,
+		AdapterReplicas:           2,
+		AdapterUnhealthyThreshold: 3,
+		RebalanceTolerancePercent: 20,
+		LeaderLeaseTTL:            30 * time.Second,
 	}
 
 	if err := envstruct.Load(&cfg); err != nil {
 		log.Fatalf("failed to load config from environment: %s", err)
 	}
 
-	hostports, err := resolveAddrs(cfg.AdapterAddrs, cfg.AdapterPort)
-	if err != nil {
-		log.Fatalf("failed to resolve adapter addrs: %s", err)
+	if cfg.LeaderID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Fatalf("failed to get hostname for LEADER_ID: %s", err)
+		}
+		cfg.LeaderID = hostname
 	}
-	cfg.AdapterAddrs = hostports
 
-	return &cfg, nil
-}
+	switch {
+	case cfg.AdapterAddrsFile != "":
+		cfg.AdapterSource = egress.NewFileSource(cfg.AdapterAddrsFile)
+	case len(cfg.AdapterAddrs) > 0 && cfg.AdapterPort != "":
+		cfg.AdapterSource = egress.NewDNSSource(cfg.AdapterAddrs, cfg.AdapterPort)
+	default:
+		log.Fatalf("either ADAPTER_ADDRS and ADAPTER_PORT or ADAPTER_ADDRS_FILE must be set")
+	}
 
-// resolveAddrs does two things:
-// 1. Does a DNS lookup of the addresses to ensure they are valid.
-// 2. Adds the given port to create hostport.
-func resolveAddrs(hosts []string, port string) ([]string, error) {
-	var hostports []string
-	for _, h := range hosts {
-		resolved, err := net.LookupIP(h)
-		if err != nil {
-			return nil, err
-		}
+	zones, err := cfg.AdapterSource.Adapters()
+	if err != nil {
+		log.Fatalf("failed to resolve adapter addrs: %s", err)
+	}
 
-		for _, h := range resolved {
-			hostports = append(hostports, fmt.Sprintf("%s:%s", h, port))
-		}
+	cfg.AdapterAddrs = make([]string, 0, len(zones))
+	for hostport := range zones {
+		cfg.AdapterAddrs = append(cfg.AdapterAddrs, hostport)
 	}
+	cfg.AdapterZones = zones
 
-	return hostports, nil
+	return &cfg, nil
 }
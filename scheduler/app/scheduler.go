@@ -10,6 +10,7 @@ import (
 	"code.cloudfoundry.org/scalable-syslog/internal/health"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/leader"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -22,6 +23,7 @@ import (
 type Scheduler struct {
 	apiURL           string
 	apiBatchSize     int
+	resyncInterval   time.Duration
 	adapterAddrs     []string
 	adapterTLSConfig *tls.Config
 	healthAddr       string
@@ -32,6 +34,16 @@ type Scheduler struct {
 	fetcher          *ingress.FilteredBindingFetcher
 	logClient        LogClient
 	blacklist        *ingress.BlacklistRanges
+	replicas         int
+	zones            map[string]string
+	source           egress.AdapterSource
+	unhealthyAfter   int
+	maxMoves         int
+	tolerance        float64
+	leaderLock       leader.Lock
+	leaseOnAdapters  bool
+	leaderID         string
+	leaderTTL        time.Duration
 }
 
 // Emitter sends gauge metrics
@@ -57,12 +69,15 @@ func NewScheduler(
 	s := &Scheduler{
 		apiURL:           apiURL,
 		apiBatchSize:     1000,
+		resyncInterval:   10 * time.Minute,
 		adapterAddrs:     adapterAddrs,
 		adapterTLSConfig: adapterTLSConfig,
 		healthAddr:       "127.0.0.1:8080",
 		client:           http.DefaultClient,
 		interval:         15 * time.Second,
 		blacklist:        &ingress.BlacklistRanges{},
+		replicas:         2,
+		unhealthyAfter:   3,
 		health:           health.NewHealth(),
 		logClient:        logClient,
 		emitter:          e,
@@ -107,6 +122,16 @@ func WithAPIBatchSize(size int) func(*Scheduler) {
 	}
 }
 
+// WithAPIResyncInterval sets the interval after which all bindings are
+// fetched from the syslog drain binding provider even if the provider
+// reports that they did not change. Drains are validated on every poll
+// regardless. It defaults to 10 minutes.
+func WithAPIResyncInterval(interval time.Duration) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.resyncInterval = interval
+	}
+}
+
 // WithBlacklist sets the blacklist for the syslog IPs.
 func WithBlacklist(r *ingress.BlacklistRanges) func(*Scheduler) {
 	return func(s *Scheduler) {
@@ -114,6 +139,75 @@ func WithBlacklist(r *ingress.BlacklistRanges) func(*Scheduler) {
 	}
 }
 
+// WithAdapterReplicas sets the number of adapters each syslog drain is
+// written to. Drains may override it with the replicas query parameter. It
+// defaults to 2.
+func WithAdapterReplicas(n int) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.replicas = n
+	}
+}
+
+// WithAdapterZones sets the availability zone of the adapters by address.
+// The replicas of a drain are spread across zones where possible. Adapters
+// without a configured zone are placed in the zone they report.
+func WithAdapterZones(zones map[string]string) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.zones = zones
+	}
+}
+
+// WithAdapterSource sets the source the adapters are discovered from at the
+// start of every term. Without a source the adapters are fixed.
+func WithAdapterSource(source egress.AdapterSource) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.source = source
+	}
+}
+
+// WithAdapterUnhealthyThreshold sets the number of consecutive failed health
+// checks after which an adapter receives no bindings. It defaults to 3.
+func WithAdapterUnhealthyThreshold(n int) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.unhealthyAfter = n
+	}
+}
+
+// WithRebalancing moves up to maxMoves bindings per term to even out the
+// load of the adapters. Adapters within the tolerance of the average load
+// are left alone. The tolerance is a fraction of the average, e.g. 0.2 for
+// 20%. Rebalancing is disabled if maxMoves is zero, which is the default.
+func WithRebalancing(maxMoves int, tolerance float64) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.maxMoves = maxMoves
+		s.tolerance = tolerance
+	}
+}
+
+// WithLeaderElection elects one of several schedulers that share the lock
+// to orchestrate the adapters. The others are standbys until the leader
+// fails to renew its lease within the ttl. Without a lock every scheduler
+// orchestrates the adapters.
+func WithLeaderElection(lock leader.Lock, id string, ttl time.Duration) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.leaderLock = lock
+		s.leaderID = id
+		s.leaderTTL = ttl
+	}
+}
+
+// WithAdapterLeaderElection elects one of several schedulers to orchestrate
+// the adapters like WithLeaderElection, with a lease that a majority of the
+// adapters grant. Unlike a lock file it works for schedulers on different
+// VMs, as long as they discover the same adapters.
+func WithAdapterLeaderElection(id string, ttl time.Duration) func(*Scheduler) {
+	return func(s *Scheduler) {
+		s.leaseOnAdapters = true
+		s.leaderID = id
+		s.leaderTTL = ttl
+	}
+}
+
 // Start starts polling the syslog drain binding provider and serves the HTTP
 // health endpoint.
 func (s *Scheduler) Start() string {
@@ -131,6 +225,7 @@ func (s *Scheduler) setupIngress() {
 			Addr:      s.apiURL,
 			BatchSize: s.apiBatchSize,
 		},
+		ingress.WithResyncInterval(s.resyncInterval),
 	)
 
 	s.fetcher = ingress.NewFilteredBindingFetcher(s.blacklist, fetcher, s.logClient)
@@ -145,14 +240,51 @@ func (s *Scheduler) startEgress() {
 		PermitWithoutStream: true,
 	}
 
-	pool := egress.NewAdapterPool(s.adapterAddrs, s.health, s.emitter,
+	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(kp),
-	)
-	orchestrator := egress.NewOrchestrator(pool, s.fetcher, pool, s.health, s.emitter)
+	}
+	pool := egress.NewAdapterPool(s.adapterAddrs, s.health, s.emitter, dialOpts...)
+	opts := []egress.OrchestratorOption{
+		egress.WithReplicas(s.replicas),
+		egress.WithZones(s.zones),
+		egress.WithAdapterSource(s.source),
+		egress.WithHealthChecker(pool, s.unhealthyAfter),
+		egress.WithRebalancing(s.maxMoves, s.tolerance),
+	}
+	if s.leaseOnAdapters {
+		s.leaderLock = leader.NewAdapterLock(s.currentAdapters, dialOpts...)
+	}
+	if s.leaderLock != nil {
+		elector := leader.NewElector(s.leaderLock, s.leaderID, s.leaderTTL, s.health, s.emitter)
+		go elector.Run()
+		opts = append(opts, egress.WithLeadership(elector))
+	}
+
+	orchestrator := egress.NewOrchestrator(pool, s.fetcher, pool, s.health, s.emitter, opts...)
 	go orchestrator.Run(s.interval)
 }
 
+// currentAdapters returns the addresses of the adapters from the adapter
+// source or the fixed adapters without a source.
+func (s *Scheduler) currentAdapters() ([]string, error) {
+	if s.source == nil {
+		return s.adapterAddrs, nil
+	}
+
+	adapters, err := s.source.Adapters()
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(adapters))
+	for addr := range adapters {
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, nil
+}
+
 func (s *Scheduler) serveHealth() string {
 	return health.StartServer(s.health, s.healthAddr)
 }
@@ -101,6 +101,42 @@ var _ = Describe("Scheduler", func() {
 		Eventually(f).Should(ConsistOf(expectedRequests))
 	})
 
+	It("sends the certificates and pins of drains to the adapters", func() {
+		dataSource := httptest.NewServer(&fakeCC{
+			body: `
+				{
+					"results": {
+						"9be15160-4845-4f05-b089-40e827ba61f1": {
+							"drains": [
+								{
+									"url": "syslog-tls://1.1.1.1:6514",
+									"cert": "some-cert",
+									"key": "some-key",
+									"ca": "some-ca",
+									"spki_pins": ["some-pin"]
+								}
+							],
+							"hostname": "org.space.name"
+						}
+					}
+				}
+			`,
+		})
+		_, spyAdapterServers := startScheduler(dataSource.URL, 1, testhelper.NewMetricClient(), false, defaultOps())
+
+		var req *v1.CreateBindingRequest
+		Eventually(spyAdapterServers[0].ActualCreateBindingRequest).Should(Receive(&req))
+		Expect(req.Binding).To(Equal(&v1.Binding{
+			AppId:      "9be15160-4845-4f05-b089-40e827ba61f1",
+			Drain:      "syslog-tls://1.1.1.1:6514",
+			Hostname:   "org.space.name",
+			ClientCert: "some-cert",
+			ClientKey:  "some-key",
+			CaCert:     "some-ca",
+			SpkiPins:   "some-pin",
+		}))
+	})
+
 	It("tells the adapters to delete bindings", func() {
 		dataSource := httptest.NewServer(&fakeCC{
 			withEmptyResult: true,
@@ -237,6 +273,7 @@ type fakeCC struct {
 	withEmptyResult bool
 	withRenamedApps bool
 	results         results
+	body            string
 }
 
 func (f *fakeCC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -255,6 +292,11 @@ func (f *fakeCC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if f.body != "" {
+		w.Write([]byte(f.body))
+		return
+	}
+
 	f.serveWithResults(w, r)
 }
 
@@ -0,0 +1,105 @@
+package egress
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultUnhealthyThreshold is the number of consecutive failed health
+	// checks after which an adapter is considered unhealthy.
+	defaultUnhealthyThreshold = 3
+
+	healthCheckTimeout = 5 * time.Second
+)
+
+// HealthChecker checks the health of the adapter at the given address.
+type HealthChecker interface {
+	Check(ctx context.Context, addr string) error
+}
+
+// AdapterState is the health of an adapter as reported on the health
+// endpoint.
+type AdapterState struct {
+	Healthy             bool `json:"healthy"`
+	ConsecutiveFailures int  `json:"consecutiveFailures"`
+}
+
+// WithHealthChecker checks the health of every adapter at the start of each
+// term. Adapters that fail the given number of consecutive checks receive no
+// bindings and their bindings are assigned to other adapters until they
+// pass a check again. Thresholds below one are ignored.
+func WithHealthChecker(c HealthChecker, unhealthyThreshold int) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.checker = c
+		if unhealthyThreshold > 0 {
+			o.unhealthyThreshold = unhealthyThreshold
+		}
+	}
+}
+
+// checkHealth checks the adapters concurrently and reports their states.
+// States of adapters that left the pool are discarded.
+func (o *Orchestrator) checkHealth(ctx context.Context, adapters []adapter) {
+	if o.checker == nil {
+		return
+	}
+
+	errs := make([]error, len(adapters))
+	var wg sync.WaitGroup
+	for i, a := range adapters {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			errs[i] = o.checker.Check(ctx, addr)
+		}(i, a.addr)
+	}
+	wg.Wait()
+
+	states := make(map[string]*AdapterState, len(adapters))
+	report := make(map[string]AdapterState, len(adapters))
+	var unhealthy int
+	for i, a := range adapters {
+		s, ok := o.states[a.addr]
+		if !ok {
+			s = &AdapterState{Healthy: true}
+		}
+
+		if errs[i] != nil {
+			s.ConsecutiveFailures++
+			if s.Healthy && s.ConsecutiveFailures >= o.unhealthyThreshold {
+				log.Printf("adapter %s is unhealthy: %s", a.addr, errs[i])
+				s.Healthy = false
+			}
+		} else {
+			if !s.Healthy {
+				log.Printf("adapter %s recovered", a.addr)
+			}
+			s.Healthy = true
+			s.ConsecutiveFailures = 0
+		}
+
+		if !s.Healthy {
+			unhealthy++
+		}
+		states[a.addr] = s
+		report[a.addr] = *s
+	}
+	o.states = states
+
+	o.unhealthyGauge.Set(float64(unhealthy))
+	o.health.SetCounter(map[string]int{"unhealthyAdapterCount": unhealthy})
+	o.health.SetValue("adapters", report)
+}
+
+// healthy returns false for adapters that failed their health checks.
+func (o *Orchestrator) healthy(addr string) bool {
+	s, ok := o.states[addr]
+	return !ok || s.Healthy
+}
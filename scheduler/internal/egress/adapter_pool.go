@@ -2,17 +2,25 @@ package egress
 
 import (
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+	"fmt"
 	"log"
+	"math"
 
 	"context"
 
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 type AdapterPool struct {
 	badConn pulseemitter.CounterMetric
+	health  HealthEmitter
+	opts    []grpc.DialOption
+	conns   map[string]*grpc.ClientConn
 	Pool    map[string]v1.AdapterClient
 }
 
@@ -20,29 +28,53 @@ func NewAdapterPool(addrs []string, h HealthEmitter, m MetricEmitter, opts ...gr
 	badConnMetrics := m.NewCounterMetric("bad_adapter_connections")
 	adapterPool := AdapterPool{
 		badConn: badConnMetrics,
+		health:  h,
+		opts:    opts,
+		conns:   map[string]*grpc.ClientConn{},
 		Pool:    map[string]v1.AdapterClient{},
 	}
+	adapterPool.Update(addrs)
 
+	return adapterPool
+}
+
+// Update dials the adapters that are not in the pool yet and closes the
+// connections to adapters that are no longer given.
+func (p AdapterPool) Update(addrs []string) {
+	current := make(map[string]bool, len(addrs))
 	for _, addr := range addrs {
-		_, ok := adapterPool.Pool[addr]
+		current[addr] = true
+
+		_, ok := p.Pool[addr]
 		if ok {
 			continue
 		}
 
-		conn, err := grpc.Dial(addr, opts...)
+		conn, err := grpc.Dial(addr, p.opts...)
 		if err != nil {
 			log.Printf("error dialing adapter: %v", err)
-			badConnMetrics.Increment(uint64(1))
+			p.badConn.Increment(uint64(1))
 			continue
 		}
-		adapterPool.Pool[addr] = v1.NewAdapterClient(conn)
+		p.conns[addr] = conn
+		p.Pool[addr] = v1.NewAdapterClient(conn)
 	}
 
-	if h != nil {
-		h.SetCounter(map[string]int{"adapterCount": len(adapterPool.Pool)})
+	for addr := range p.Pool {
+		if current[addr] {
+			continue
+		}
+
+		if conn, ok := p.conns[addr]; ok {
+			conn.Close()
+			delete(p.conns, addr)
+		}
+		delete(p.Pool, addr)
 	}
 
-	return adapterPool
+	if p.health != nil {
+		p.health.SetCounter(map[string]int{"adapterCount": len(p.Pool)})
+	}
 }
 
 func (p AdapterPool) List(ctx context.Context, adapter interface{}) ([]interface{}, error) {
@@ -53,9 +85,11 @@ func (p AdapterPool) List(ctx context.Context, adapter interface{}) ([]interface
 		return nil, err
 	}
 
+	// Adapters that predate binding identities list the credentials of
+	// their bindings, which are not kept.
 	var bindings []interface{}
 	for _, b := range results.Bindings {
-		bindings = append(bindings, *b)
+		bindings = append(bindings, v1.Identity(*b))
 	}
 
 	return bindings, nil
@@ -78,3 +112,58 @@ func (p AdapterPool) Remove(ctx context.Context, adapter, task interface{}) erro
 
 	return err
 }
+
+func (p AdapterPool) Load(ctx context.Context, adapter interface{}) (Load, error) {
+	resp, err := adapter.(v1.AdapterClient).GetLoad(ctx, &v1.GetLoadRequest{})
+	if err != nil {
+		return Load{}, err
+	}
+
+	load := Load{
+		IngressRate: resp.IngressRate,
+		EgressRate:  resp.EgressRate,
+		BufferFill:  resp.BufferFill,
+		Connections: int(resp.Connections),
+		MaxBindings: int(resp.MaxBindings),
+		Zone:        resp.Zone,
+		Draining:    resp.Draining,
+		Bindings:    make(map[v1.Binding]float64),
+		Streaming:   make(map[v1.Binding]bool),
+	}
+	for _, bl := range resp.Bindings {
+		if bl.Binding == nil {
+			continue
+		}
+		id := v1.Identity(*bl.Binding)
+		load.Bindings[id] = math.Max(bl.IngressRate, bl.EgressRate)
+		if bl.Streaming {
+			load.Streaming[id] = true
+		}
+	}
+
+	return load, nil
+}
+
+// Check checks the adapter at the given address with the gRPC health
+// protocol. Adapters that do not implement the protocol are considered
+// healthy.
+func (p AdapterPool) Check(ctx context.Context, addr string) error {
+	conn, ok := p.conns[addr]
+	if !ok {
+		return fmt.Errorf("unknown adapter %s", addr)
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("adapter %s is %s", addr, resp.Status)
+	}
+
+	return nil
+}
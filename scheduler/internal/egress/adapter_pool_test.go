@@ -6,6 +6,8 @@ import (
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
@@ -100,14 +102,110 @@ var _ = Describe("AdapterPool", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(results).To(HaveLen(2))
 	})
+
+	It("adds and removes adapters", func() {
+		health := &spyHealthEmitter{}
+		adapterPool := egress.NewAdapterPool([]string{
+			"0.0.0.0:1234",
+			"0.0.0.0:1235",
+		}, health, spyMetricClient, grpc.WithInsecure())
+		client := adapterPool.Pool["0.0.0.0:1234"]
+
+		adapterPool.Update([]string{
+			"0.0.0.0:1234",
+			"0.0.0.0:1236",
+		})
+
+		Expect(adapterPool.Pool).To(HaveLen(2))
+		Expect(adapterPool.Pool).To(HaveKey("0.0.0.0:1236"))
+		Expect(adapterPool.Pool).ToNot(HaveKey("0.0.0.0:1235"))
+		Expect(adapterPool.Pool["0.0.0.0:1234"]).To(BeIdenticalTo(client))
+		Expect(health.setCounterArg).To(Equal(map[string]int{"adapterCount": 2}))
+	})
+
+	Describe("Check", func() {
+		It("considers adapters without the health protocol healthy", func() {
+			addr, cleanup := startGRPCServer()
+			defer cleanup()
+
+			adapterPool := egress.NewAdapterPool([]string{addr}, nil, spyMetricClient, grpc.WithInsecure())
+
+			err := adapterPool.Check(context.Background(), addr)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if the adapter is not serving", func() {
+			healthServer := grpchealth.NewServer()
+			addr, cleanup := startGRPCServer(healthServer)
+			defer cleanup()
+			adapterPool := egress.NewAdapterPool([]string{addr}, nil, spyMetricClient, grpc.WithInsecure())
+
+			err := adapterPool.Check(context.Background(), addr)
+			Expect(err).ToNot(HaveOccurred())
+
+			healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+			err = adapterPool.Check(context.Background(), addr)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error for an unknown adapter", func() {
+			adapterPool := egress.NewAdapterPool(nil, nil, spyMetricClient, grpc.WithInsecure())
+
+			err := adapterPool.Check(context.Background(), "0.0.0.0:1234")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("returns the load of the given adapter", func() {
+		addr, cleanup := startGRPCServer()
+		defer cleanup()
+
+		adapterPool := egress.NewAdapterPool([]string{addr}, nil, spyMetricClient, grpc.WithInsecure())
+		binding := v1.Binding{AppId: "some-app-id"}
+		err := adapterPool.Add(context.Background(), adapterPool.Pool[addr], binding)
+		Expect(err).ToNot(HaveOccurred())
+
+		load, err := adapterPool.Load(context.Background(), adapterPool.Pool[addr])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.Bindings).To(HaveKey(binding))
+		Expect(load.Zone).To(Equal("some-zone"))
+		Expect(load.Streaming).To(HaveKey(binding))
+	})
+
+	It("keeps the bindings of the given adapter by their identity", func() {
+		addr, cleanup := startGRPCServer()
+		defer cleanup()
+
+		adapterPool := egress.NewAdapterPool([]string{addr}, nil, spyMetricClient, grpc.WithInsecure())
+		binding := v1.Binding{
+			AppId:      "some-app-id",
+			Drain:      "syslog-tls://some.url",
+			ClientCert: "some-cert",
+			ClientKey:  "some-key",
+		}
+		err := adapterPool.Add(context.Background(), adapterPool.Pool[addr], binding)
+		Expect(err).ToNot(HaveOccurred())
+
+		results, err := adapterPool.List(context.Background(), adapterPool.Pool[addr])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(v1.Identity(binding)))
+
+		load, err := adapterPool.Load(context.Background(), adapterPool.Pool[addr])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.Bindings).To(HaveKey(v1.Identity(binding)))
+		Expect(load.Streaming).To(HaveKey(v1.Identity(binding)))
+	})
 })
 
-func startGRPCServer() (string, func()) {
+func startGRPCServer(healthServer ...healthpb.HealthServer) (string, func()) {
 	lis, err := net.Listen("tcp", "localhost:0")
 	Expect(err).NotTo(HaveOccurred())
 	testServer := newSpyAdapterServer()
 	grpcServer := grpc.NewServer()
 	v1.RegisterAdapterServer(grpcServer, testServer)
+	for _, h := range healthServer {
+		healthpb.RegisterHealthServer(grpcServer, h)
+	}
 
 	go grpcServer.Serve(lis)
 
@@ -119,8 +217,16 @@ func startGRPCServer() (string, func()) {
 
 type spyHealthEmitter struct {
 	setCounterArg map[string]int
+	values        map[string]interface{}
 }
 
 func (s *spyHealthEmitter) SetCounter(m map[string]int) {
 	s.setCounterArg = m
 }
+
+func (s *spyHealthEmitter) SetValue(name string, v interface{}) {
+	if s.values == nil {
+		s.values = make(map[string]interface{})
+	}
+	s.values[name] = v
+}
@@ -0,0 +1,109 @@
+package egress
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// AdapterSource discovers the adapters. Adapters returns the availability
+// zone of each adapter by hostport. The zone is empty if it is unknown.
+type AdapterSource interface {
+	Adapters() (map[string]string, error)
+}
+
+// DNSSource resolves the hosts of the adapters on every call so that
+// adapters that are added to or removed from DNS are picked up.
+type DNSSource struct {
+	hosts []string
+	port  string
+}
+
+// NewDNSSource returns a DNSSource for the given hosts. A host may be
+// followed by @ and the availability zone of the adapters it resolves to,
+// e.g. adapter.z1.example.com@z1. The port is added to every resolved IP.
+func NewDNSSource(hosts []string, port string) *DNSSource {
+	return &DNSSource{
+		hosts: hosts,
+		port:  port,
+	}
+}
+
+// Adapters resolves the hosts. It fails if any host does not resolve so
+// that a DNS outage does not remove adapters.
+func (s *DNSSource) Adapters() (map[string]string, error) {
+	adapters := make(map[string]string)
+	for _, h := range s.hosts {
+		host, zone := splitZone(h)
+
+		resolved, err := net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range resolved {
+			adapters[net.JoinHostPort(ip.String(), s.port)] = zone
+		}
+	}
+
+	return adapters, nil
+}
+
+// FileSource reads the adapters from a file on every call so that the file
+// can be updated while the scheduler is running. The file lists one
+// hostport per line, optionally followed by @ and the availability zone of
+// the adapter. Blank lines and lines starting with # are ignored.
+type FileSource struct {
+	path string
+}
+
+// NewFileSource returns a FileSource for the file at the given path.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{
+		path: path,
+	}
+}
+
+// Adapters reads the file. It fails if the file can not be read or has an
+// invalid hostport.
+func (s *FileSource) Adapters() (map[string]string, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	adapters := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		addr, zone := splitZone(line)
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid adapter address %q: %s", addr, err)
+		}
+		adapters[addr] = zone
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return adapters, nil
+}
+
+// splitZone splits an address into the address and the availability zone
+// that follows the last @.
+func splitZone(addr string) (string, string) {
+	i := strings.LastIndex(addr, "@")
+	if i < 0 {
+		return addr, ""
+	}
+
+	return addr[:i], addr[i+1:]
+}
@@ -0,0 +1,94 @@
+package egress_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AdapterSource", func() {
+	Describe("DNSSource", func() {
+		It("resolves the hosts and adds the port", func() {
+			source := egress.NewDNSSource([]string{
+				"127.0.0.1@z1",
+				"127.0.0.2",
+			}, "1234")
+
+			adapters, err := source.Adapters()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adapters).To(Equal(map[string]string{
+				"127.0.0.1:1234": "z1",
+				"127.0.0.2:1234": "",
+			}))
+		})
+	})
+
+	Describe("FileSource", func() {
+		var path string
+
+		BeforeEach(func() {
+			f, err := ioutil.TempFile("", "adapters")
+			Expect(err).ToNot(HaveOccurred())
+			f.Close()
+			path = f.Name()
+		})
+
+		AfterEach(func() {
+			os.Remove(path)
+		})
+
+		write := func(content string) {
+			err := ioutil.WriteFile(path, []byte(content), 0600)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		It("reads the adapters from the file", func() {
+			write("# adapters\n10.0.0.1:4443@z1\n\n10.0.0.2:4443\n")
+			source := egress.NewFileSource(path)
+
+			adapters, err := source.Adapters()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adapters).To(Equal(map[string]string{
+				"10.0.0.1:4443": "z1",
+				"10.0.0.2:4443": "",
+			}))
+		})
+
+		It("picks up changes to the file", func() {
+			write("10.0.0.1:4443\n")
+			source := egress.NewFileSource(path)
+			_, err := source.Adapters()
+			Expect(err).ToNot(HaveOccurred())
+
+			write("10.0.0.2:4443\n")
+			adapters, err := source.Adapters()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adapters).To(HaveLen(1))
+			Expect(adapters).To(HaveKey("10.0.0.2:4443"))
+		})
+
+		It("returns an error for an invalid address", func() {
+			write("10.0.0.1\n")
+			source := egress.NewFileSource(path)
+
+			_, err := source.Adapters()
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns an error if the file does not exist", func() {
+			source := egress.NewFileSource("/does/not/exist")
+
+			_, err := source.Adapters()
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
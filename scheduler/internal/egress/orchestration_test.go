@@ -2,13 +2,21 @@ package egress_test
 
 import (
 	"errors"
+	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
 
 	"context"
 
+	"google.golang.org/grpc"
+
+	loggregator "code.cloudfoundry.org/go-loggregator"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/egress"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -27,12 +35,14 @@ var _ = Describe("Orchestration", func() {
 
 		comm         *spyCommunicator
 		adapterCount *testhelper.SpyMetric
+
+		client1, client2, client3 *spyClient
 	)
 
 	BeforeEach(func() {
-		client1 := &spyClient{}
-		client2 := &spyClient{}
-		client3 := &spyClient{}
+		client1 = &spyClient{}
+		client2 = &spyClient{}
+		client3 = &spyClient{}
 		comm = newSpyCommunicator()
 
 		mc := testhelper.NewMetricClient()
@@ -64,6 +74,8 @@ var _ = Describe("Orchestration", func() {
 			clientList := []*spyClient{client1, client2, client3}
 			comm.listResults = map[interface{}][]interface{}{}
 			for i, b := range bs {
+				// Adapters list their bindings by identity.
+				b = v1.Identity(b)
 				comm.listResults[clientList[(i*2)%len(clientList)]] = append(
 					comm.listResults[clientList[(i*2)%len(clientList)]],
 					b,
@@ -110,6 +122,103 @@ var _ = Describe("Orchestration", func() {
 		Expect(comm.removes).To(HaveLen(0))
 	})
 
+	It("keeps a binding with credentials that the adapters list by identity", func() {
+		b := v1.Binding{AppId: "a", Drain: "syslog-tls://d", ClientCert: "some-cert", ClientKey: "some-key"}
+		updateBindingList([]v1.Binding{b})
+		updateBindings([]v1.Binding{b}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds).To(BeEmpty())
+		Expect(comm.removes).To(BeEmpty())
+	})
+
+	It("replaces a binding whose client certificate changed", func() {
+		old := v1.Binding{AppId: "a", Drain: "syslog-tls://d", ClientCert: "old-cert", ClientKey: "old-key"}
+		updateBindingList([]v1.Binding{old})
+
+		renewed := v1.Binding{AppId: "a", Drain: "syslog-tls://d", ClientCert: "new-cert", ClientKey: "new-key"}
+		updateBindings([]v1.Binding{renewed}, nil)
+
+		nextTerm()
+
+		var adds, removes []interface{}
+		for _, bs := range comm.adds {
+			adds = append(adds, bs...)
+		}
+		for _, bs := range comm.removes {
+			removes = append(removes, bs...)
+		}
+		Expect(adds).To(ConsistOf(renewed, renewed))
+		Expect(removes).To(ConsistOf(v1.Identity(old), v1.Identity(old)))
+	})
+
+	It("sends the certificates and pins of the binding provider to the adapters", func() {
+		getter := &spyGetter{body: `
+			{
+			  "results": {
+				"app-id": {
+				  "drains": [{"url": "syslog-tls://10.10.10.10:6514", "cert": "some-cert", "key": "some-key", "ca": "some-ca", "spki_pins": ["pin-1", "pin-2"]}],
+				  "hostname": "org.space.app"
+				}
+			  }
+			}
+		`}
+		blacklist, err := ingress.NewBlacklistRanges()
+		Expect(err).ToNot(HaveOccurred())
+		filter := ingress.NewFilteredBindingFetcher(
+			blacklist,
+			ingress.NewBindingFetcher(getter),
+			&spyLogClient{},
+		)
+		orch := egress.NewOrchestrator(
+			egress.AdapterPool{
+				Pool: map[string]v1.AdapterClient{
+					"test-addr-1": client1,
+					"test-addr-2": client2,
+				},
+			},
+			filter,
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+		)
+
+		orch.NextTerm()
+
+		expected := v1.Binding{
+			AppId:      "app-id",
+			Hostname:   "org.space.app",
+			Drain:      "syslog-tls://10.10.10.10:6514",
+			ClientCert: "some-cert",
+			ClientKey:  "some-key",
+			CaCert:     "some-ca",
+			SpkiPins:   "pin-1,pin-2",
+		}
+		Expect(comm.adds[client1]).To(ConsistOf(expected))
+		Expect(comm.adds[client2]).To(ConsistOf(expected))
+	})
+
+	It("replaces a binding whose CA bundle changed", func() {
+		old := v1.Binding{AppId: "a", Drain: "https://d", CaCert: "old-ca"}
+		updateBindingList([]v1.Binding{old})
+
+		renewed := v1.Binding{AppId: "a", Drain: "https://d", CaCert: "new-ca", SpkiPins: "some-pin"}
+		updateBindings([]v1.Binding{renewed}, nil)
+
+		nextTerm()
+
+		var adds, removes []interface{}
+		for _, bs := range comm.adds {
+			adds = append(adds, bs...)
+		}
+		for _, bs := range comm.removes {
+			removes = append(removes, bs...)
+		}
+		Expect(adds).To(ConsistOf(renewed, renewed))
+		Expect(removes).To(ConsistOf(v1.Identity(old), v1.Identity(old)))
+	})
+
 	It("removes a binding", func() {
 		updateBindingList([]v1.Binding{
 			{AppId: "a"},
@@ -202,6 +311,594 @@ var _ = Describe("Orchestration", func() {
 
 		Expect(comm.removes).To(HaveLen(0))
 	})
+
+	It("adds each binding to the number of adapters its drain requests", func() {
+		single := v1.Binding{AppId: "a", Drain: "syslog://a.example.com?replicas=1"}
+		triple := v1.Binding{AppId: "b", Drain: "syslog://b.example.com?replicas=3"}
+		updateBindings([]v1.Binding{single, triple}, nil)
+
+		nextTerm()
+
+		var singles, triples int
+		for _, bindings := range comm.adds {
+			for _, b := range bindings {
+				switch b {
+				case single:
+					singles++
+				case triple:
+					triples++
+				}
+			}
+		}
+		Expect(singles).To(Equal(1))
+		Expect(triples).To(Equal(3))
+	})
+
+	It("adds each binding to the configured number of adapters", func() {
+		orch := egress.NewOrchestrator(
+			egress.AdapterPool{
+				Pool: map[string]v1.AdapterClient{
+					"test-addr-1": client1,
+					"test-addr-2": client2,
+					"test-addr-3": client3,
+				},
+			},
+			&spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithReplicas(3),
+		)
+
+		orch.NextTerm()
+
+		Expect(comm.adds).To(HaveLen(3))
+	})
+
+	It("adds bindings to the least loaded adapters", func() {
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {EgressRate: 1000},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds[client1]).To(BeEmpty())
+		Expect(comm.adds[client2]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.adds[client3]).To(ConsistOf(v1.Binding{AppId: "a"}))
+	})
+
+	It("places heavy bindings before light bindings", func() {
+		heavy := v1.Binding{AppId: "heavy"}
+		light := v1.Binding{AppId: "light"}
+		comm.listResults = map[interface{}][]interface{}{
+			client1: {heavy},
+			client2: {light},
+		}
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {
+				EgressRate: 1000,
+				Bindings:   map[v1.Binding]float64{heavy: 1000},
+			},
+			client2: {
+				EgressRate: 1,
+				Bindings:   map[v1.Binding]float64{light: 1},
+			},
+		}
+		updateBindings([]v1.Binding{light, heavy}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds[client3]).To(ConsistOf(heavy))
+		Expect(comm.adds[client1]).To(ConsistOf(light))
+	})
+
+	It("does not add bindings to adapters at their maximum", func() {
+		comm.listResults = map[interface{}][]interface{}{
+			client1: {v1.Binding{AppId: "a"}},
+		}
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {MaxBindings: 1},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+			{AppId: "b"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds[client1]).To(BeEmpty())
+		Expect(comm.adds[client2]).To(ContainElement(v1.Binding{AppId: "b"}))
+		Expect(comm.adds[client3]).To(ContainElement(v1.Binding{AppId: "b"}))
+		Expect(len(comm.adds[client2]) + len(comm.adds[client3])).To(Equal(3))
+	})
+
+	It("only adds bindings to saturated adapters if no other adapter can take them", func() {
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {BufferFill: 0.95},
+			client2: {BufferFill: 0.95},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds[client3]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.adds).To(HaveLen(2))
+	})
+
+	It("removes excess instances of a binding", func() {
+		comm.listResults = map[interface{}][]interface{}{
+			client1: {v1.Binding{AppId: "a"}},
+			client2: {v1.Binding{AppId: "a"}},
+			client3: {v1.Binding{AppId: "a"}},
+		}
+		comm.loadResults = map[interface{}]egress.Load{
+			client2: {EgressRate: 1000},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.removes).To(HaveLen(1))
+		Expect(comm.removes[client2]).To(ConsistOf(v1.Binding{AppId: "a"}))
+	})
+
+	It("spreads the instances of a binding across the zones the adapters report", func() {
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {Zone: "z1"},
+			client2: {Zone: "z1"},
+			client3: {Zone: "z2", EgressRate: 1000},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds[client3]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.adds).To(HaveLen(2))
+	})
+
+	It("prefers configured zones over reported zones", func() {
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {Zone: "z1"},
+			client2: {Zone: "z2"},
+			client3: {Zone: "z3", EgressRate: 1000},
+		}
+		orch := egress.NewOrchestrator(
+			egress.AdapterPool{
+				Pool: map[string]v1.AdapterClient{
+					"test-addr-1": client1,
+					"test-addr-2": client2,
+					"test-addr-3": client3,
+				},
+			},
+			&spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithZones(map[string]string{
+				"test-addr-1": "z1",
+				"test-addr-2": "z1",
+				"test-addr-3": "z2",
+			}),
+		)
+
+		orch.NextTerm()
+
+		Expect(comm.adds[client3]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.adds).To(HaveLen(2))
+	})
+
+	It("removes excess instances from zones with more than one instance", func() {
+		comm.listResults = map[interface{}][]interface{}{
+			client1: {v1.Binding{AppId: "a"}},
+			client2: {v1.Binding{AppId: "a"}},
+			client3: {v1.Binding{AppId: "a"}},
+		}
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {Zone: "z1"},
+			client2: {Zone: "z1"},
+			client3: {Zone: "z2", EgressRate: 1000},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.removes).To(HaveLen(1))
+		Expect(comm.removes[client3]).To(BeEmpty())
+	})
+
+	It("moves bindings off draining adapters once the replacements stream", func() {
+		comm.listResults = map[interface{}][]interface{}{
+			client1: {v1.Binding{AppId: "a"}},
+		}
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {Draining: true},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds[client1]).To(BeEmpty())
+		Expect(comm.adds[client2]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.adds[client3]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.removes).To(BeEmpty())
+
+		comm.listResults[client2] = []interface{}{v1.Binding{AppId: "a"}}
+		comm.listResults[client3] = []interface{}{v1.Binding{AppId: "a"}}
+		comm.loadResults[client2] = egress.Load{
+			Streaming: map[v1.Binding]bool{{AppId: "a"}: true},
+		}
+
+		nextTerm()
+		Expect(comm.removes).To(BeEmpty())
+
+		comm.loadResults[client3] = egress.Load{
+			Streaming: map[v1.Binding]bool{{AppId: "a"}: true},
+		}
+
+		nextTerm()
+		Expect(comm.removes[client1]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.adds).To(HaveLen(2))
+	})
+
+	It("keeps bindings on a draining adapter if no other adapter can take them", func() {
+		comm.listResults = map[interface{}][]interface{}{
+			client1: {v1.Binding{AppId: "a"}},
+		}
+		comm.loadResults = map[interface{}]egress.Load{
+			client1: {Draining: true},
+			client2: {Draining: true},
+			client3: {Draining: true},
+		}
+		updateBindings([]v1.Binding{
+			{AppId: "a"},
+		}, nil)
+
+		nextTerm()
+
+		Expect(comm.adds).To(BeEmpty())
+		Expect(comm.removes).To(BeEmpty())
+	})
+
+	Describe("rebalancing", func() {
+		newOrchestrator := func(drains []v1.Binding, opts ...egress.OrchestratorOption) *egress.Orchestrator {
+			return egress.NewOrchestrator(
+				egress.AdapterPool{
+					Pool: map[string]v1.AdapterClient{
+						"test-addr-1": client1,
+						"test-addr-2": client2,
+						"test-addr-3": client3,
+					},
+				},
+				&spyReader{drains: drains},
+				comm,
+				&spyHealthEmitter{},
+				testhelper.NewMetricClient(),
+				opts...,
+			)
+		}
+
+		It("moves a limited number of bindings to an idle adapter", func() {
+			var drains []v1.Binding
+			var held []interface{}
+			for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+				drains = append(drains, v1.Binding{AppId: id})
+				held = append(held, v1.Binding{AppId: id})
+			}
+			comm.listResults = map[interface{}][]interface{}{
+				client1: held,
+				client2: held,
+			}
+			orch := newOrchestrator(drains, egress.WithRebalancing(2, 0.2))
+
+			orch.NextTerm()
+
+			Expect(comm.adds).To(HaveLen(1))
+			Expect(comm.adds[client3]).To(HaveLen(2))
+			Expect(comm.removes).To(BeEmpty())
+		})
+
+		It("removes moved bindings from the old adapter once they stream on the new one", func() {
+			streaming := make(map[v1.Binding]bool)
+			var drains []v1.Binding
+			var held []interface{}
+			for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+				b := v1.Binding{AppId: id}
+				drains = append(drains, b)
+				held = append(held, b)
+				streaming[b] = true
+			}
+			comm.listResults = map[interface{}][]interface{}{
+				client1: held,
+				client2: held,
+			}
+			comm.loadResults = map[interface{}]egress.Load{
+				client1: {Streaming: streaming},
+				client2: {Streaming: streaming},
+			}
+			orch := newOrchestrator(drains, egress.WithRebalancing(1, 0.2))
+
+			orch.NextTerm()
+			Expect(comm.adds[client3]).To(HaveLen(1))
+			moved := comm.adds[client3][0].(v1.Binding)
+			comm.listResults[client3] = []interface{}{moved}
+
+			By("keeping the old instance while the new one is not streaming")
+			orch.NextTerm()
+			Expect(comm.removes).To(BeEmpty())
+
+			By("removing the old instance once the new one is streaming")
+			comm.loadResults[client3] = egress.Load{
+				Streaming: map[v1.Binding]bool{moved: true},
+			}
+			orch.NextTerm()
+
+			var removes []interface{}
+			for _, bs := range comm.removes {
+				removes = append(removes, bs...)
+			}
+			Expect(removes).To(ConsistOf(moved))
+		})
+
+		It("does not move bindings of adapters within the tolerance", func() {
+			comm.listResults = map[interface{}][]interface{}{
+				client1: {v1.Binding{AppId: "a"}, v1.Binding{AppId: "b"}},
+				client2: {v1.Binding{AppId: "c"}, v1.Binding{AppId: "d"}},
+				client3: {v1.Binding{AppId: "e"}},
+			}
+			orch := newOrchestrator(
+				[]v1.Binding{{AppId: "a"}, {AppId: "b"}, {AppId: "c"}, {AppId: "d"}, {AppId: "e"}},
+				egress.WithReplicas(1),
+				egress.WithRebalancing(10, 0.25),
+			)
+
+			orch.NextTerm()
+
+			Expect(comm.adds).To(BeEmpty())
+			Expect(comm.removes).To(BeEmpty())
+		})
+
+		It("stops moving bindings once the adapters are balanced", func() {
+			comm.listResults = map[interface{}][]interface{}{
+				client1: {v1.Binding{AppId: "a"}, v1.Binding{AppId: "b"}, v1.Binding{AppId: "c"}},
+				client2: {v1.Binding{AppId: "d"}, v1.Binding{AppId: "e"}},
+				client3: {v1.Binding{AppId: "f"}},
+			}
+			orch := newOrchestrator(
+				[]v1.Binding{{AppId: "a"}, {AppId: "b"}, {AppId: "c"}, {AppId: "d"}, {AppId: "e"}, {AppId: "f"}},
+				egress.WithReplicas(1),
+				egress.WithRebalancing(10, 0.2),
+			)
+
+			orch.NextTerm()
+
+			Expect(comm.adds).To(HaveLen(1))
+			Expect(comm.adds[client3]).To(HaveLen(1))
+			Expect(comm.removes).To(BeEmpty())
+		})
+	})
+
+	It("adds and removes the adapters of the adapter source", func() {
+		pool := egress.NewAdapterPool(
+			[]string{"127.0.0.1:1001"},
+			nil,
+			testhelper.NewMetricClient(),
+			grpc.WithInsecure(),
+		)
+		source := &spyAdapterSource{
+			adapters: map[string]string{
+				"127.0.0.1:1001": "",
+				"127.0.0.1:1002": "",
+			},
+		}
+		orch := egress.NewOrchestrator(
+			pool,
+			&spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithAdapterSource(source),
+		)
+
+		orch.NextTerm()
+
+		Expect(pool.Pool).To(HaveLen(2))
+		Expect(comm.adds[pool.Pool["127.0.0.1:1001"]]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(comm.adds[pool.Pool["127.0.0.1:1002"]]).To(ConsistOf(v1.Binding{AppId: "a"}))
+
+		comm.listResults = map[interface{}][]interface{}{
+			pool.Pool["127.0.0.1:1001"]: {v1.Binding{AppId: "a"}},
+		}
+		source.adapters = map[string]string{
+			"127.0.0.1:1001": "",
+			"127.0.0.1:1003": "",
+		}
+
+		orch.NextTerm()
+
+		Expect(pool.Pool).To(HaveLen(2))
+		Expect(pool.Pool).ToNot(HaveKey("127.0.0.1:1002"))
+		Expect(comm.adds[pool.Pool["127.0.0.1:1003"]]).To(ConsistOf(v1.Binding{AppId: "a"}))
+	})
+
+	It("assigns no bindings to adapters that fail consecutive health checks", func() {
+		checker := &spyHealthChecker{
+			errs: map[string]error{"test-addr-1": errors.New("some-error")},
+		}
+		health := &spyHealthEmitter{}
+		comm.listResults = map[interface{}][]interface{}{
+			client1: {v1.Binding{AppId: "a"}},
+			client2: {v1.Binding{AppId: "a"}},
+		}
+		orch := egress.NewOrchestrator(
+			egress.AdapterPool{
+				Pool: map[string]v1.AdapterClient{
+					"test-addr-1": client1,
+					"test-addr-2": client2,
+					"test-addr-3": client3,
+				},
+			},
+			&spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			comm,
+			health,
+			testhelper.NewMetricClient(),
+			egress.WithHealthChecker(checker, 2),
+		)
+
+		orch.NextTerm()
+		Expect(comm.adds).To(BeEmpty())
+
+		orch.NextTerm()
+		Expect(comm.adds[client3]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		Expect(health.setCounterArg).To(HaveKeyWithValue("unhealthyAdapterCount", 1))
+		Expect(health.values["adapters"]).To(HaveKeyWithValue("test-addr-1", egress.AdapterState{
+			Healthy:             false,
+			ConsecutiveFailures: 2,
+		}))
+
+		comm.listResults[client3] = []interface{}{v1.Binding{AppId: "a"}}
+		checker.errs = nil
+
+		orch.NextTerm()
+		Expect(comm.removes).To(HaveLen(1))
+		Expect(health.values["adapters"]).To(HaveKeyWithValue("test-addr-1", egress.AdapterState{
+			Healthy: true,
+		}))
+	})
+
+	It("does not orchestrate while it is not the leader", func() {
+		leadership := &spyLeadership{}
+		orch := egress.NewOrchestrator(
+			egress.AdapterPool{
+				Pool: map[string]v1.AdapterClient{
+					"test-addr-1": client1,
+					"test-addr-2": client2,
+				},
+			},
+			&spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithLeadership(leadership),
+		)
+
+		orch.NextTerm()
+		Expect(comm.adds).To(BeEmpty())
+
+		leadership.leader = true
+		orch.NextTerm()
+		Expect(comm.adds).To(HaveLen(2))
+	})
+
+	It("stops the term once it is no longer the leader", func() {
+		leadership := &spyLeadership{leader: true, lostAfter: 2}
+		orch := egress.NewOrchestrator(
+			egress.AdapterPool{
+				Pool: map[string]v1.AdapterClient{
+					"test-addr-1": client1,
+					"test-addr-2": client2,
+				},
+			},
+			&spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithLeadership(leadership),
+		)
+
+		orch.NextTerm()
+		Expect(comm.adds).To(HaveLen(1))
+	})
+
+	Describe("unchanged terms", func() {
+		var (
+			reader *spyChangeReportingReader
+			orch   *egress.Orchestrator
+		)
+
+		BeforeEach(func() {
+			reader = &spyChangeReportingReader{
+				spyReader: spyReader{drains: []v1.Binding{{AppId: "a"}}},
+				changed:   true,
+			}
+			comm.listResults = map[interface{}][]interface{}{
+				client1: {v1.Binding{AppId: "a"}},
+			}
+			orch = egress.NewOrchestrator(
+				egress.AdapterPool{
+					Pool: map[string]v1.AdapterClient{
+						"test-addr-1": client1,
+						"test-addr-2": client2,
+					},
+				},
+				reader,
+				comm,
+				&spyHealthEmitter{},
+				testhelper.NewMetricClient(),
+				egress.WithReplicas(1),
+			)
+
+			orch.NextTerm()
+			Expect(comm.adds).To(BeEmpty())
+			Expect(comm.removes).To(BeEmpty())
+		})
+
+		It("skips the plan while the bindings and adapters do not change", func() {
+			reader.drains = []v1.Binding{{AppId: "b"}}
+			reader.changed = false
+
+			orch.NextTerm()
+			Expect(comm.adds).To(BeEmpty())
+			Expect(comm.removes).To(BeEmpty())
+
+			reader.changed = true
+
+			orch.NextTerm()
+			Expect(comm.adds).To(HaveLen(1))
+			Expect(comm.removes[client1]).To(ConsistOf(v1.Binding{AppId: "a"}))
+		})
+
+		It("plans if the adapters changed", func() {
+			reader.changed = false
+			comm.listResults[client2] = []interface{}{v1.Binding{AppId: "a"}}
+
+			orch.NextTerm()
+			Expect(comm.removes).To(HaveLen(1))
+		})
+	})
+
+	It("keeps the adapters if the adapter source fails", func() {
+		source := &spyAdapterSource{err: errors.New("some-error")}
+		orch := egress.NewOrchestrator(
+			egress.AdapterPool{
+				Pool: map[string]v1.AdapterClient{
+					"test-addr-1": client1,
+					"test-addr-2": client2,
+				},
+			},
+			&spyReader{drains: []v1.Binding{{AppId: "a"}}},
+			comm,
+			&spyHealthEmitter{},
+			testhelper.NewMetricClient(),
+			egress.WithAdapterSource(source),
+		)
+
+		orch.NextTerm()
+
+		Expect(comm.adds).To(HaveLen(2))
+	})
 })
 
 func hasDuplicate(bindings []interface{}) bool {
@@ -218,6 +915,8 @@ func hasDuplicate(bindings []interface{}) bool {
 type spyCommunicator struct {
 	listResults map[interface{}][]interface{}
 	listErrs    map[interface{}]error
+	loadResults map[interface{}]egress.Load
+	loadErrs    map[interface{}]error
 	addsErr     map[interface{}]error
 	removesErr  map[interface{}]error
 	adds        map[interface{}][]interface{}
@@ -249,6 +948,48 @@ func (s *spyCommunicator) Remove(ctx context.Context, worker, task interface{})
 	return s.removesErr[worker]
 }
 
+func (s *spyCommunicator) Load(ctx context.Context, adapter interface{}) (egress.Load, error) {
+	return s.loadResults[adapter], s.loadErrs[adapter]
+}
+
+type spyAdapterSource struct {
+	adapters map[string]string
+	err      error
+}
+
+func (s *spyAdapterSource) Adapters() (map[string]string, error) {
+	return s.adapters, s.err
+}
+
+type spyHealthChecker struct {
+	mu   sync.Mutex
+	errs map[string]error
+}
+
+func (s *spyHealthChecker) Check(ctx context.Context, addr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.errs[addr]
+}
+
+// spyLeadership loses the leadership after lostAfter calls to IsLeader if
+// lostAfter is set.
+type spyLeadership struct {
+	leader    bool
+	lostAfter int
+	calls     int
+}
+
+func (s *spyLeadership) IsLeader() bool {
+	s.calls++
+	if s.lostAfter > 0 && s.calls > s.lostAfter {
+		s.leader = false
+	}
+
+	return s.leader
+}
+
 type spyReader struct {
 	drains []v1.Binding
 	err    error
@@ -258,6 +999,15 @@ func (s *spyReader) FetchBindings() (appBindings []v1.Binding, invalid int, err
 	return s.randomizeOrder(s.drains), 0, s.err
 }
 
+type spyChangeReportingReader struct {
+	spyReader
+	changed bool
+}
+
+func (s *spyChangeReportingReader) Changed() bool {
+	return s.changed
+}
+
 func (s *spyReader) randomizeOrder(b []v1.Binding) []v1.Binding {
 	var result []v1.Binding
 	for _, n := range rand.Perm(len(b)) {
@@ -265,3 +1015,18 @@ func (s *spyReader) randomizeOrder(b []v1.Binding) []v1.Binding {
 	}
 	return result
 }
+
+type spyGetter struct {
+	body string
+}
+
+func (s *spyGetter) Get(nextID int) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(s.body)),
+	}, nil
+}
+
+type spyLogClient struct{}
+
+func (s *spyLogClient) EmitLog(message string, opts ...loggregator.EmitLogOption) {}
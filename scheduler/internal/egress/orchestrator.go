@@ -3,31 +3,140 @@ package egress
 
 import (
 	"context"
+	"hash/fnv"
 	"log"
+	"net/url"
+	"sort"
+	"strconv"
 	"time"
 
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
-	orchestrator "code.cloudfoundry.org/go-orchestrator"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 )
 
-const maxAdapters = 2
+// defaultReplicas is the number of adapters each binding is written to
+// unless configured otherwise.
+const defaultReplicas = 2
 
 type BindingReader interface {
 	FetchBindings() (appBindings []v1.Binding, invalid int, err error)
 }
 
+// ChangeReporter is implemented by BindingReaders that can tell whether the
+// bindings changed since the previous fetch.
+type ChangeReporter interface {
+	Changed() bool
+}
+
 type HealthEmitter interface {
 	SetCounter(c map[string]int)
+	SetValue(name string, v interface{})
 }
 
 // Orchestrator manages writes to a number of adapters.
 type Orchestrator struct {
-	reader     BindingReader
-	comm       Communicator
-	orch       *orchestrator.Orchestrator
-	health     HealthEmitter
-	drainGauge pulseemitter.GaugeMetric
+	reader       BindingReader
+	comm         Communicator
+	pool         AdapterPool
+	source       AdapterSource
+	zones        map[string]string
+	health       HealthEmitter
+	drainGauge   pulseemitter.GaugeMetric
+	adapterGauge pulseemitter.GaugeMetric
+	replicas     int
+	leadership   Leadership
+
+	// settled is set if the previous term had nothing to do for the
+	// adapters with the given fingerprint.
+	settled     bool
+	fingerprint uint64
+
+	maxMoves         int
+	tolerance        float64
+	rebalancedMetric pulseemitter.CounterMetric
+
+	// moves are the bindings that were moved off an adapter by rebalancing
+	// and stay on it until their new instance is streaming.
+	moves map[move]bool
+
+	checker            HealthChecker
+	unhealthyThreshold int
+	states             map[string]*AdapterState
+	unhealthyGauge     pulseemitter.GaugeMetric
+}
+
+// move is a binding that is released from the adapter with the given
+// address.
+type move struct {
+	binding v1.Binding
+	from    string
+}
+
+// adapter is a client of an adapter and its configured availability zone.
+type adapter struct {
+	addr   string
+	client interface{}
+	zone   string
+}
+
+// OrchestratorOption configures an Orchestrator.
+type OrchestratorOption func(*Orchestrator)
+
+// WithReplicas sets the number of adapters each binding is written to.
+// Drains may override it with the replicas query parameter. Values below
+// one are ignored.
+func WithReplicas(n int) OrchestratorOption {
+	return func(o *Orchestrator) {
+		if n > 0 {
+			o.replicas = n
+		}
+	}
+}
+
+// WithZones sets the availability zone of the adapters by address. Adapters
+// without a configured zone are placed in the zone they report.
+func WithZones(zones map[string]string) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.zones = zones
+	}
+}
+
+// WithRebalancing moves up to maxMoves bindings per term from adapters that
+// are busier than the average by more than the tolerance to adapters that
+// are less busy than the average by more than the tolerance. The tolerance
+// is a fraction of the average, e.g. 0.2 for 20%. Rebalancing is disabled by
+// default.
+func WithRebalancing(maxMoves int, tolerance float64) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.maxMoves = maxMoves
+		o.tolerance = tolerance
+	}
+}
+
+// Leadership tells whether the scheduler is the leader.
+type Leadership interface {
+	IsLeader() bool
+}
+
+// WithLeadership skips terms while the scheduler is not the leader so that
+// only one of several schedulers orchestrates the adapters. The leadership
+// is checked again before every binding that is added or removed, and the
+// rest of the term is skipped once the leadership is lost.
+func WithLeadership(l Leadership) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.leadership = l
+	}
+}
+
+// WithAdapterSource sets the source the adapters are discovered from at the
+// start of every term. Adapters that are added to the source join the
+// placement and the bindings of adapters that are removed from it are
+// assigned to the remaining adapters. The zones the source returns replace
+// the zones given by WithZones.
+func WithAdapterSource(s AdapterSource) OrchestratorOption {
+	return func(o *Orchestrator) {
+		o.source = s
+	}
 }
 
 type Communicator interface {
@@ -44,6 +153,11 @@ type Communicator interface {
 	// is either not doing the task because the worker is down, or there is a
 	// network partition and a future term will fix the problem.
 	Remove(ctx context.Context, adapter, binding interface{}) error
+
+	// Load returns the load reported by the given adapter. Bindings are
+	// assigned to adapters that fail to report their load as if they were
+	// idle.
+	Load(ctx context.Context, adapter interface{}) (Load, error)
 }
 
 type MetricEmitter interface {
@@ -58,6 +172,7 @@ func NewOrchestrator(
 	c Communicator,
 	h HealthEmitter,
 	m MetricEmitter,
+	opts ...OrchestratorOption,
 ) *Orchestrator {
 	// metric-documentation-v2: (scheduler.drains) Number of drains being
 	// serviced by scalable syslog.
@@ -69,25 +184,47 @@ func NewOrchestrator(
 		pulseemitter.WithVersion(2, 0),
 	)
 
-	orch := orchestrator.New(c,
-		orchestrator.WithStats(func(s orchestrator.TermStats) {
-			adapterGauge.Set(float64(s.WorkerCount))
-		}),
+	// metric-documentation-v2: (scheduler.rebalanced_bindings) Number of
+	// bindings moved between adapters to even out their load.
+	rebalancedMetric := m.NewCounterMetric("rebalanced_bindings",
+		pulseemitter.WithVersion(2, 0),
+	)
+
+	// metric-documentation-v2: (scheduler.unhealthy_adapters) Number of
+	// adapters that failed their health checks and receive no bindings.
+	unhealthyGauge := m.NewGaugeMetric("unhealthy_adapters", "count",
+		pulseemitter.WithVersion(2, 0),
 	)
-	for _, client := range adapterPool.Pool {
-		orch.AddWorker(client)
-	}
 
-	return &Orchestrator{
-		reader:     r,
-		comm:       c,
-		health:     h,
-		drainGauge: drainGauge,
-		orch:       orch,
+	o := &Orchestrator{
+		reader:       r,
+		comm:         c,
+		pool:         adapterPool,
+		health:       h,
+		drainGauge:   drainGauge,
+		adapterGauge: adapterGauge,
+		replicas:     defaultReplicas,
+
+		rebalancedMetric: rebalancedMetric,
+
+		unhealthyThreshold: defaultUnhealthyThreshold,
+		states:             make(map[string]*AdapterState),
+		unhealthyGauge:     unhealthyGauge,
+	}
+	for _, opt := range opts {
+		opt(o)
 	}
+
+	return o
 }
 
 func (o *Orchestrator) NextTerm() {
+	if !o.leading() {
+		return
+	}
+
+	o.discover()
+
 	freshBindings, blacklisted, err := o.reader.FetchBindings()
 	if err != nil {
 		log.Printf("fetch bindings failed with error: %s", err)
@@ -100,16 +237,232 @@ func (o *Orchestrator) NextTerm() {
 	})
 	o.drainGauge.Set(float64(len(freshBindings)))
 
-	var tasks []orchestrator.Task
+	ctx := context.Background()
+	workers := o.workers(ctx)
+	o.adapterGauge.Set(float64(len(workers)))
+	if len(workers) == 0 {
+		return
+	}
+
+	o.markMoves(workers)
+	fingerprint := fingerprintOf(workers)
+	unchanged := o.settled && fingerprint == o.fingerprint && !o.bindingsChanged()
+
+	// The bindings are placed by their identity, as the adapters report
+	// them. The credentials are only sent along when a binding is added.
+	identities := make([]v1.Binding, 0, len(freshBindings))
+	credentials := make(map[v1.Binding]v1.Binding, len(freshBindings))
 	for _, b := range freshBindings {
-		tasks = append(tasks, orchestrator.Task{
-			Name:      b,
-			Instances: maxAdapters,
+		id := v1.Identity(b)
+		identities = append(identities, id)
+		credentials[id] = b
+	}
+
+	p := newPlacement(workers)
+	var actions []action
+	if !unchanged {
+		actions = p.plan(identities, o.instances)
+	}
+	moves := p.rebalance(o.maxMoves, o.tolerance)
+	o.rebalancedMetric.Increment(uint64(len(moves)))
+	o.moves = movesOf(workers)
+
+	o.settled = len(actions) == 0 && len(moves) == 0
+	o.fingerprint = fingerprint
+
+	for _, a := range append(actions, moves...) {
+		// The lease may expire while the term runs. A standby that took
+		// over must not see its assignments changed by a former leader.
+		if !o.leading() {
+			log.Printf("lost leadership, skipping the rest of the term")
+			o.settled = false
+			return
+		}
+
+		if a.remove {
+			if err := o.comm.Remove(ctx, a.adapter, a.binding); err != nil {
+				log.Printf("failed to remove binding from adapter: %s", err)
+			}
+			continue
+		}
+
+		b, ok := credentials[a.binding]
+		if !ok {
+			b = a.binding
+		}
+		if err := o.comm.Add(ctx, a.adapter, b); err != nil {
+			log.Printf("failed to add binding to adapter: %s", err)
+		}
+	}
+}
+
+// leading returns true if the scheduler is the leader or if there is no
+// leader election.
+func (o *Orchestrator) leading() bool {
+	return o.leadership == nil || o.leadership.IsLeader()
+}
+
+// instances returns the number of adapters the binding is written to. The
+// replicas query parameter of the drain URL takes precedence over the
+// configured number of replicas.
+func (o *Orchestrator) instances(b v1.Binding) int {
+	u, err := url.Parse(b.Drain)
+	if err != nil {
+		return o.replicas
+	}
+
+	n, err := strconv.Atoi(u.Query().Get("replicas"))
+	if err != nil || n < 1 {
+		return o.replicas
+	}
+
+	return n
+}
+
+// discover updates the adapter pool from the adapter source. The pool is
+// left as it is if the source fails.
+func (o *Orchestrator) discover() {
+	if o.source == nil {
+		return
+	}
+
+	adapters, err := o.source.Adapters()
+	if err != nil {
+		log.Printf("failed to discover adapters: %s", err)
+		return
+	}
+
+	addrs := make([]string, 0, len(adapters))
+	for addr := range adapters {
+		addrs = append(addrs, addr)
+	}
+	o.pool.Update(addrs)
+	o.zones = adapters
+}
+
+// adapters returns the adapters of the pool ordered by address.
+func (o *Orchestrator) adapters() []adapter {
+	addrs := make([]string, 0, len(o.pool.Pool))
+	for addr := range o.pool.Pool {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	adapters := make([]adapter, 0, len(addrs))
+	for _, addr := range addrs {
+		adapters = append(adapters, adapter{
+			addr:   addr,
+			client: o.pool.Pool[addr],
+			zone:   o.zones[addr],
 		})
 	}
 
-	o.orch.UpdateTasks(tasks)
-	o.orch.NextTerm(context.Background())
+	return adapters
+}
+
+// workers lists the bindings and load of every adapter. Adapters that are
+// unhealthy or fail to list their bindings are left out of the term.
+func (o *Orchestrator) workers(ctx context.Context) []*worker {
+	adapters := o.adapters()
+	o.checkHealth(ctx, adapters)
+
+	var workers []*worker
+	for _, a := range adapters {
+		if !o.healthy(a.addr) {
+			continue
+		}
+
+		bindings, err := o.comm.List(ctx, a.client)
+		if err != nil {
+			continue
+		}
+
+		load, err := o.comm.Load(ctx, a.client)
+		if err != nil {
+			log.Printf("failed to get adapter load: %s", err)
+		}
+		if a.zone != "" {
+			load.Zone = a.zone
+		}
+
+		workers = append(workers, newWorker(a.addr, a.client, bindings, load))
+	}
+
+	return workers
+}
+
+// markMoves marks the bindings that were moved off the workers in previous
+// terms as releasing. Moves of bindings the worker no longer has are
+// forgotten.
+func (o *Orchestrator) markMoves(workers []*worker) {
+	byAddr := make(map[string]*worker, len(workers))
+	for _, w := range workers {
+		byAddr[w.addr] = w
+	}
+
+	for m := range o.moves {
+		w, ok := byAddr[m.from]
+		if !ok || !w.bindings[m.binding] {
+			delete(o.moves, m)
+			continue
+		}
+
+		w.releasing[m.binding] = true
+	}
+}
+
+// movesOf returns the bindings the workers are releasing.
+func movesOf(workers []*worker) map[move]bool {
+	moves := make(map[move]bool)
+	for _, w := range workers {
+		for b := range w.releasing {
+			moves[move{binding: b, from: w.addr}] = true
+		}
+	}
+
+	return moves
+}
+
+// bindingsChanged returns false if the reader reports that the bindings did
+// not change since the previous term.
+func (o *Orchestrator) bindingsChanged() bool {
+	cr, ok := o.reader.(ChangeReporter)
+	return !ok || cr.Changed()
+}
+
+// fingerprintOf returns a hash of the bindings, zones and drain states of
+// the workers that does not depend on their order. The bindings are hashed
+// by their identity, which covers their credentials by digest. The plan of a
+// term with the same bindings and fingerprint as a settled term would be
+// empty, so it is skipped.
+func fingerprintOf(workers []*worker) uint64 {
+	var sum uint64
+	for _, w := range workers {
+		sum += hashOf(w.addr, w.zone, strconv.FormatBool(w.draining()))
+		for b := range w.bindings {
+			sum += hashOf(
+				w.addr,
+				b.AppId,
+				b.Hostname,
+				b.Drain,
+				b.CredentialsDigest,
+				strconv.FormatBool(w.load.Streaming[b]),
+				strconv.FormatBool(w.releasing[b]),
+			)
+		}
+	}
+
+	return sum
+}
+
+func hashOf(fields ...string) uint64 {
+	h := fnv.New64a()
+	for _, f := range fields {
+		h.Write([]byte(f))
+		h.Write([]byte{0})
+	}
+
+	return h.Sum64()
 }
 
 // Run starts the orchestrator.
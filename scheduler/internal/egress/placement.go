@@ -0,0 +1,401 @@
+package egress
+
+import (
+	"math"
+	"sort"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+)
+
+// saturatedBufferFill is the average buffer fill at which an adapter is
+// considered unable to keep up with its drains. Saturated adapters only
+// receive bindings if no other adapter can take them.
+const saturatedBufferFill = 0.9
+
+// Load is the utilization an adapter reports. Rates are in envelopes per
+// second.
+type Load struct {
+	IngressRate float64
+	EgressRate  float64
+	BufferFill  float64
+	Connections int
+	MaxBindings int
+
+	// Zone is the availability zone of the adapter.
+	Zone string
+
+	// Draining is set while the adapter waits for its bindings to be moved
+	// before it shuts down.
+	Draining bool
+
+	// Streaming is set for the bindings that have an open log stream.
+	Streaming map[v1.Binding]bool
+
+	// Bindings is the observed volume of each binding of the adapter.
+	Bindings map[v1.Binding]float64
+}
+
+// worker is an adapter that responded during a term. Its rate, connections
+// and bindings are updated as the placement assigns and removes bindings.
+// Releasing bindings have been moved to another worker and are removed
+// from this worker once the new instance is streaming.
+type worker struct {
+	addr        string
+	adapter     interface{}
+	zone        string
+	bindings    map[v1.Binding]bool
+	added       map[v1.Binding]bool
+	releasing   map[v1.Binding]bool
+	load        Load
+	rate        float64
+	connections float64
+}
+
+func newWorker(addr string, adapter interface{}, bindings []interface{}, load Load) *worker {
+	w := &worker{
+		addr:        addr,
+		adapter:     adapter,
+		zone:        load.Zone,
+		bindings:    make(map[v1.Binding]bool),
+		added:       make(map[v1.Binding]bool),
+		releasing:   make(map[v1.Binding]bool),
+		load:        load,
+		rate:        math.Max(load.IngressRate, load.EgressRate),
+		connections: float64(load.Connections),
+	}
+	for _, b := range bindings {
+		w.bindings[b.(v1.Binding)] = true
+	}
+
+	return w
+}
+
+// assigned returns the number of bindings of the worker that are not being
+// released.
+func (w *worker) assigned() int {
+	return len(w.bindings) - len(w.releasing)
+}
+
+func (w *worker) full() bool {
+	return w.load.MaxBindings > 0 && len(w.bindings) >= w.load.MaxBindings
+}
+
+func (w *worker) saturated() bool {
+	return w.load.BufferFill >= saturatedBufferFill
+}
+
+func (w *worker) draining() bool {
+	return w.load.Draining
+}
+
+// action adds a binding to or removes a binding from an adapter.
+type action struct {
+	adapter interface{}
+	binding v1.Binding
+	remove  bool
+}
+
+// placement assigns bindings to workers by their load. Bindings that are
+// already assigned stay where they are. Missing instances go to the workers
+// with the lowest score, heaviest bindings first, so heavy drains are spread
+// out instead of piling up on the adapter with the fewest bindings. The
+// instances of a binding are spread across availability zones where
+// possible. Bindings of draining workers and releasing bindings are placed
+// on other workers as if they were gone, and are removed once their
+// replacements are streaming.
+type placement struct {
+	workers []*worker
+	volume  map[v1.Binding]float64
+
+	// defaultVolume is assumed for bindings that no adapter has reported.
+	defaultVolume float64
+
+	meanRate        float64
+	meanConnections float64
+	meanBindings    float64
+}
+
+func newPlacement(workers []*worker) *placement {
+	p := &placement{
+		workers: workers,
+		volume:  make(map[v1.Binding]float64),
+	}
+
+	for _, w := range workers {
+		for b, v := range w.load.Bindings {
+			p.volume[b] = math.Max(p.volume[b], v)
+		}
+	}
+
+	var total float64
+	for _, v := range p.volume {
+		total += v
+	}
+	if len(p.volume) > 0 {
+		p.defaultVolume = total / float64(len(p.volume))
+	}
+
+	return p
+}
+
+func (p *placement) volumeOf(b v1.Binding) float64 {
+	if v, ok := p.volume[b]; ok {
+		return v
+	}
+
+	return p.defaultVolume
+}
+
+// plan returns the actions that give every binding its number of instances
+// on distinct workers and remove bindings that are no longer desired or
+// have been replaced on draining workers or after a move.
+func (p *placement) plan(bindings []v1.Binding, instances func(v1.Binding) int) []action {
+	desired := make(map[v1.Binding]bool, len(bindings))
+	for _, b := range bindings {
+		desired[b] = true
+	}
+
+	var actions []action
+	holders := make(map[v1.Binding][]*worker)
+	for _, w := range p.workers {
+		for b := range w.bindings {
+			if !desired[b] {
+				actions = append(actions, p.remove(w, b))
+				continue
+			}
+			if w.draining() || w.releasing[b] {
+				continue
+			}
+			holders[b] = append(holders[b], w)
+		}
+	}
+
+	var excess, missing []v1.Binding
+	counts := make(map[v1.Binding]int)
+	for _, b := range bindings {
+		if _, ok := counts[b]; ok {
+			continue
+		}
+
+		n := len(holders[b])
+		counts[b] = instances(b) - n
+		switch {
+		case counts[b] < 0:
+			excess = append(excess, b)
+		case counts[b] > 0:
+			missing = append(missing, b)
+		}
+	}
+
+	p.updateMeans(missing, counts)
+
+	for _, b := range excess {
+		actions = append(actions, p.trim(b, holders[b], -counts[b])...)
+	}
+
+	sort.SliceStable(missing, func(i, j int) bool {
+		return p.volumeOf(missing[i]) > p.volumeOf(missing[j])
+	})
+
+	for _, b := range missing {
+		for i := 0; i < counts[b]; i++ {
+			w := p.candidate(b)
+			if w == nil {
+				break
+			}
+			actions = append(actions, p.add(w, b))
+		}
+	}
+
+	return append(actions, p.release(instances)...)
+}
+
+// release removes the bindings of draining workers and the releasing
+// bindings of other workers once they are streaming on as many other
+// workers as they have instances, or on all other workers if there are
+// fewer. Bindings that no other worker can take stay where they are.
+func (p *placement) release(instances func(v1.Binding) int) []action {
+	var actions []action
+	for _, w := range p.workers {
+		for b := range w.bindings {
+			if !w.draining() && !w.releasing[b] {
+				continue
+			}
+
+			var available, streaming int
+			for _, other := range p.workers {
+				if other == w || other.draining() {
+					continue
+				}
+				available++
+
+				if other.bindings[b] && !other.releasing[b] && other.load.Streaming[b] {
+					streaming++
+				}
+			}
+
+			need := instances(b)
+			if need > available {
+				need = available
+			}
+
+			if need > 0 && streaming >= need {
+				actions = append(actions, p.remove(w, b))
+			}
+		}
+	}
+
+	return actions
+}
+
+// trim removes the given number of instances of the binding from the
+// busiest workers, starting with workers in zones that hold more than one
+// instance.
+func (p *placement) trim(b v1.Binding, holders []*worker, excess int) []action {
+	vol := p.volumeOf(b)
+	sort.SliceStable(holders, func(i, j int) bool {
+		return p.score(holders[i], vol) > p.score(holders[j], vol)
+	})
+
+	var actions []action
+	for ; excess > 0; excess-- {
+		zones := make(map[string]int)
+		for _, w := range holders {
+			zones[w.zone]++
+		}
+
+		i := 0
+		for j, w := range holders {
+			if w.zone != "" && zones[w.zone] > 1 {
+				i = j
+				break
+			}
+		}
+
+		actions = append(actions, p.remove(holders[i], b))
+		holders = append(holders[:i], holders[i+1:]...)
+	}
+
+	return actions
+}
+
+// updateMeans computes the average rate, connections and bindings per
+// worker once all missing instances are placed. Scores are relative to
+// these averages so that the dimensions are comparable.
+func (p *placement) updateMeans(missing []v1.Binding, counts map[v1.Binding]int) {
+	var rate, connections, bindings, n float64
+	for _, w := range p.workers {
+		if w.draining() {
+			continue
+		}
+
+		n++
+		rate += w.rate
+		connections += w.connections
+		bindings += float64(w.assigned())
+	}
+
+	for _, b := range missing {
+		n := float64(counts[b])
+		rate += n * p.volumeOf(b)
+		connections += n
+		bindings += n
+	}
+
+	if n == 0 {
+		return
+	}
+
+	p.meanRate = rate / n
+	p.meanConnections = connections / n
+	p.meanBindings = bindings / n
+}
+
+// score rates how busy the worker would be with an additional binding of
+// the given volume. Lower is better.
+func (p *placement) score(w *worker, volume float64) float64 {
+	return share(w.rate+volume, p.meanRate) +
+		share(w.connections+1, p.meanConnections) +
+		share(float64(w.assigned()+1), p.meanBindings) +
+		w.load.BufferFill
+}
+
+func share(value, mean float64) float64 {
+	if mean <= 0 {
+		return 0
+	}
+
+	return value / mean
+}
+
+// candidate returns the worker with the lowest score that does not have the
+// binding yet and has room for it. Workers in a zone that does not hold the
+// binding yet are preferred. Saturated workers are only returned if no other
+// worker qualifies.
+func (p *placement) candidate(b v1.Binding) *worker {
+	vol := p.volumeOf(b)
+
+	used := make(map[string]bool)
+	for _, w := range p.workers {
+		if w.bindings[b] && !w.releasing[b] && w.zone != "" {
+			used[w.zone] = true
+		}
+	}
+
+	// Candidates are ranked by saturation first and zone second.
+	var ranked [4]*worker
+	for _, w := range p.workers {
+		if w.bindings[b] || w.full() || w.draining() {
+			continue
+		}
+
+		var rank int
+		if w.saturated() {
+			rank += 2
+		}
+		if used[w.zone] {
+			rank++
+		}
+
+		if ranked[rank] == nil || p.score(w, vol) < p.score(ranked[rank], vol) {
+			ranked[rank] = w
+		}
+	}
+
+	for _, w := range ranked {
+		if w != nil {
+			return w
+		}
+	}
+
+	return nil
+}
+
+func (p *placement) add(w *worker, b v1.Binding) action {
+	w.bindings[b] = true
+	w.added[b] = true
+	w.rate += p.volumeOf(b)
+	w.connections++
+
+	return action{adapter: w.adapter, binding: b}
+}
+
+// moveOut marks the binding of the worker as releasing. It stays on the
+// worker until release removes it.
+func (p *placement) moveOut(w *worker, b v1.Binding) {
+	w.releasing[b] = true
+	w.rate = math.Max(0, w.rate-p.volumeOf(b))
+	w.connections = math.Max(0, w.connections-1)
+}
+
+func (p *placement) remove(w *worker, b v1.Binding) action {
+	if w.releasing[b] {
+		delete(w.releasing, b)
+	} else {
+		w.rate = math.Max(0, w.rate-p.volumeOf(b))
+		w.connections = math.Max(0, w.connections-1)
+	}
+	delete(w.bindings, b)
+
+	return action{adapter: w.adapter, binding: b, remove: true}
+}
@@ -0,0 +1,142 @@
+package egress
+
+import (
+	"sort"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+)
+
+// rebalance moves up to maxMoves bindings from the busiest workers to the
+// least busy workers. Bindings are only moved while the busiest worker is
+// more than the tolerance above the average utilization and the least busy
+// worker is more than the tolerance below it, so that workers close to the
+// average are left alone and bindings do not move back and forth between
+// terms. Each move adds the binding to the new worker and marks it as
+// releasing on the old one, which keeps writing it until release removes it
+// in a later term once the new instance is streaming. Bindings that were
+// added in the same plan or are already being released are not moved.
+func (p *placement) rebalance(maxMoves int, tolerance float64) []action {
+	p.updateMeans(nil, nil)
+
+	var actions []action
+	for moves := 0; moves < maxMoves; moves++ {
+		src, dst := p.extremes()
+		if src == nil || dst == nil || src == dst {
+			break
+		}
+
+		if p.utilization(src.rate, src.connections, src.assigned()) <= 1+tolerance ||
+			p.utilization(dst.rate, dst.connections, dst.assigned()) >= 1-tolerance {
+			break
+		}
+
+		b, ok := p.movable(src, dst)
+		if !ok {
+			break
+		}
+
+		actions = append(actions, p.add(dst, b))
+		p.moveOut(src, b)
+	}
+
+	return actions
+}
+
+// extremes returns the busiest worker and the least busy worker that can
+// take another binding. Draining workers are left out.
+func (p *placement) extremes() (busiest, idlest *worker) {
+	var max, min float64
+	for _, w := range p.workers {
+		if w.draining() {
+			continue
+		}
+
+		u := p.utilization(w.rate, w.connections, w.assigned())
+		if busiest == nil || u > max {
+			busiest, max = w, u
+		}
+
+		if w.full() || w.saturated() {
+			continue
+		}
+		if idlest == nil || u < min {
+			idlest, min = w, u
+		}
+	}
+
+	return busiest, idlest
+}
+
+// movable returns the heaviest binding of src that can move to dst without
+// making dst busier than src and without putting two instances of the
+// binding in the same zone where they were in different zones before.
+func (p *placement) movable(src, dst *worker) (v1.Binding, bool) {
+	var bindings []v1.Binding
+	for b := range src.bindings {
+		if !dst.bindings[b] && !src.added[b] && !src.releasing[b] {
+			bindings = append(bindings, b)
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		vi, vj := p.volumeOf(bindings[i]), p.volumeOf(bindings[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return bindings[i].String() < bindings[j].String()
+	})
+
+	for _, b := range bindings {
+		vol := p.volumeOf(b)
+		after := p.utilization(src.rate-vol, src.connections-1, src.assigned()-1)
+		if p.utilization(dst.rate+vol, dst.connections+1, dst.assigned()+1) > after {
+			continue
+		}
+
+		if p.zoneConflict(b, src, dst) {
+			continue
+		}
+
+		return b, true
+	}
+
+	return v1.Binding{}, false
+}
+
+// zoneConflict returns true if moving the binding from src to dst would put
+// it in a zone that another instance of the binding already occupies.
+func (p *placement) zoneConflict(b v1.Binding, src, dst *worker) bool {
+	if dst.zone == "" || dst.zone == src.zone {
+		return false
+	}
+
+	for _, w := range p.workers {
+		if w != src && w.bindings[b] && !w.releasing[b] && w.zone == dst.zone {
+			return true
+		}
+	}
+
+	return false
+}
+
+// utilization is the load of a worker relative to the average worker. It is
+// 1 for a worker with average rate, connections and bindings.
+func (p *placement) utilization(rate, connections float64, bindings int) float64 {
+	var sum, n float64
+	for _, d := range []struct{ value, mean float64 }{
+		{rate, p.meanRate},
+		{connections, p.meanConnections},
+		{float64(bindings), p.meanBindings},
+	} {
+		if d.mean <= 0 {
+			continue
+		}
+		sum += d.value / d.mean
+		n++
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return sum / n
+}
@@ -53,3 +53,26 @@ func (t *spyAdapterServer) DeleteBinding(c context.Context, r *v1.DeleteBindingR
 
 	return new(v1.DeleteBindingResponse), nil
 }
+
+func (t *spyAdapterServer) GetLoad(context.Context, *v1.GetLoadRequest) (*v1.GetLoadResponse, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	resp := &v1.GetLoadResponse{Zone: "some-zone"}
+	for _, b := range t.Bindings {
+		resp.Bindings = append(resp.Bindings, &v1.BindingLoad{
+			Binding:   b,
+			Streaming: true,
+		})
+	}
+
+	return resp, nil
+}
+
+func (t *spyAdapterServer) AcquireLease(context.Context, *v1.AcquireLeaseRequest) (*v1.AcquireLeaseResponse, error) {
+	return &v1.AcquireLeaseResponse{Acquired: true}, nil
+}
+
+func (t *spyAdapterServer) ReleaseLease(context.Context, *v1.ReleaseLeaseRequest) (*v1.ReleaseLeaseResponse, error) {
+	return new(v1.ReleaseLeaseResponse), nil
+}
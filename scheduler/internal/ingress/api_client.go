@@ -18,3 +18,15 @@ type APIClient struct {
 func (w APIClient) Get(nextID int) (*http.Response, error) {
 	return w.Client.Get(fmt.Sprintf(pathTemplate, w.Addr, w.BatchSize, nextID))
 }
+
+// GetIfNoneMatch gets the page with the If-None-Match header set to the
+// given ETag.
+func (w APIClient) GetIfNoneMatch(nextID int, etag string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(pathTemplate, w.Addr, w.BatchSize, nextID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("If-None-Match", etag)
+
+	return w.Client.Do(req)
+}
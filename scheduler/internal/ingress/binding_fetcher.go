@@ -5,79 +5,239 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
 )
 
+// defaultResyncInterval is the interval after which all pages are fetched
+// unconditionally unless configured otherwise.
+const defaultResyncInterval = 10 * time.Minute
+
 // Getter is configured to fetch HTTP responses
 type Getter interface {
 	Get(nextID int) (resp *http.Response, err error)
 }
 
+// ConditionalGetter is a Getter that can ask for a page only if it changed
+// since it was served with the given ETag. The syslog drain binding
+// provider responds with 304 Not Modified if the page did not change.
+type ConditionalGetter interface {
+	Getter
+	GetIfNoneMatch(nextID int, etag string) (resp *http.Response, err error)
+}
+
 // BindingFetcher uses a Getter to fetch and decode Bindings
 type BindingFetcher struct {
-	getter     Getter
+	getter         Getter
+	resyncInterval time.Duration
+
 	mu         sync.RWMutex
 	drainCount int
+	pages      map[int]page
+	bindings   map[v1.Binding]int
+	resynced   time.Time
+	changed    bool
+}
+
+// page is a page of the syslog drain binding provider by the next_id it was
+// requested with.
+type page struct {
+	etag     string
+	bindings []v1.Binding
+	nextID   int
 }
 
 type response struct {
 	Results map[string]struct {
-		Drains   []string
+		Drains   []drain
 		Hostname string
 	}
 	NextID int `json:"next_id"`
 }
 
+// drain is a drain of an app. The provider lists drains either as their
+// URL or as an object with the URL, the PEM encoded client certificate and
+// key to present to the drain and the PEM encoded CA bundle and SPKI pins
+// to verify the drain against.
+type drain struct {
+	URL      string   `json:"url"`
+	Cert     string   `json:"cert"`
+	Key      string   `json:"key"`
+	CA       string   `json:"ca"`
+	SPKIPins []string `json:"spki_pins"`
+}
+
+func (d *drain) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &d.URL); err == nil {
+		return nil
+	}
+
+	type plain drain
+	return json.Unmarshal(b, (*plain)(d))
+}
+
+// BindingFetcherOption configures a BindingFetcher.
+type BindingFetcherOption func(*BindingFetcher)
+
+// WithResyncInterval sets the interval after which all pages are fetched
+// without If-None-Match and reported as changed, in case the provider
+// misses a change. It defaults to 10 minutes.
+func WithResyncInterval(d time.Duration) BindingFetcherOption {
+	return func(f *BindingFetcher) {
+		f.resyncInterval = d
+	}
+}
+
 // NewBindingFetcher returns a new BindingFetcher
-func NewBindingFetcher(g Getter) *BindingFetcher {
-	return &BindingFetcher{
-		getter: g,
+func NewBindingFetcher(g Getter, opts ...BindingFetcherOption) *BindingFetcher {
+	f := &BindingFetcher{
+		getter:         g,
+		resyncInterval: defaultResyncInterval,
+		changed:        true,
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
+
+	return f
 }
 
 // FetchBindings reaches out to the syslog drain binding provider via the Getter and decodes
 // the response. If it does not get a 200, it returns an error.
+//
+// If the Getter is a ConditionalGetter, pages that were served with an ETag
+// are requested with If-None-Match and reused from the previous fetch if
+// the provider responds with 304 Not Modified.
 func (f *BindingFetcher) FetchBindings() ([]v1.Binding, error) {
+	f.mu.RLock()
+	resync := f.resynced.IsZero() || time.Since(f.resynced) >= f.resyncInterval
+	cache := f.pages
+	f.mu.RUnlock()
+
+	start := time.Now()
+	pages := make(map[int]page)
 	bindings := []v1.Binding{}
 	nextID := 0
 
 	for {
-		resp, err := f.getter.Get(nextID)
+		var cached *page
+		if p, ok := cache[nextID]; ok && !resync && p.etag != "" {
+			cached = &p
+		}
+
+		p, err := f.fetchPage(nextID, cached)
 		if err != nil {
 			return nil, err
 		}
+		pages[nextID] = p
+		bindings = append(bindings, p.bindings...)
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("received %d status code from syslog drain binding API", resp.StatusCode)
+		if p.nextID == 0 {
+			break
 		}
+		nextID = p.nextID
+	}
 
-		body, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
+	counts := make(map[v1.Binding]int, len(bindings))
+	for _, b := range bindings {
+		counts[b]++
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.changed = resync || !sameBindings(counts, f.bindings)
+	f.pages = pages
+	f.bindings = counts
+	f.drainCount = len(bindings)
+	if resync {
+		f.resynced = start
+	}
+
+	return bindings, nil
+}
+
+// Changed returns true if the bindings of the last fetch differ from the
+// bindings of the fetch before it or if the last fetch was a resync.
+func (f *BindingFetcher) Changed() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.changed
+}
+
+// fetchPage fetches the page with the given next_id. The cached page is
+// returned if the provider reports that it did not change.
+func (f *BindingFetcher) fetchPage(nextID int, cached *page) (page, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+	cg, ok := f.getter.(ConditionalGetter)
+	if ok && cached != nil {
+		resp, err = cg.GetIfNoneMatch(nextID, cached.etag)
+	} else {
+		resp, err = f.getter.Get(nextID)
+	}
+	if err != nil {
+		return page{}, err
+	}
+	if resp.Body != nil {
 		defer resp.Body.Close()
+	}
 
-		var r response
-		if err = json.Unmarshal(body, &r); err != nil {
-			return nil, fmt.Errorf("invalid API response body")
-		}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return *cached, nil
+	}
 
-		for appID, bindingData := range r.Results {
-			hostname := bindingData.Hostname
-			for _, drainURL := range bindingData.Drains {
-				bindings = append(bindings, v1.Binding{
-					Hostname: hostname,
-					Drain:    drainURL,
-					AppId:    appID,
-				})
-			}
+	if resp.StatusCode != http.StatusOK {
+		return page{}, fmt.Errorf("received %d status code from syslog drain binding API", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return page{}, err
+	}
+
+	var r response
+	if err = json.Unmarshal(body, &r); err != nil {
+		return page{}, fmt.Errorf("invalid API response body")
+	}
+
+	p := page{
+		etag:   resp.Header.Get("ETag"),
+		nextID: r.NextID,
+	}
+	for appID, bindingData := range r.Results {
+		hostname := bindingData.Hostname
+		for _, d := range bindingData.Drains {
+			p.bindings = append(p.bindings, v1.Binding{
+				Hostname:   hostname,
+				Drain:      d.URL,
+				AppId:      appID,
+				ClientCert: d.Cert,
+				ClientKey:  d.Key,
+				CaCert:     d.CA,
+				SpkiPins:   strings.Join(d.SPKIPins, ","),
+			})
 		}
+	}
+
+	return p, nil
+}
 
-		if r.NextID == 0 {
-			return bindings, nil
+func sameBindings(a, b map[v1.Binding]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, n := range a {
+		if b[k] != n {
+			return false
 		}
-		nextID = r.NextID
 	}
+
+	return true
 }
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
 	. "github.com/onsi/ginkgo"
@@ -118,8 +119,207 @@ var _ = Describe("BindingFetcher", func() {
 		})
 	})
 
+	Context("when drains have client certificates", func() {
+		BeforeEach(func() {
+			getter.getResponses = []*http.Response{
+				pageResponse(`
+					{
+					  "results": {
+						"app-1": {
+						  "drains": [
+							"syslog://some.url",
+							{
+							  "url": "syslog-tls://secure.url",
+							  "cert": "some-cert",
+							  "key": "some-key"
+							}
+						  ],
+						  "hostname": "h"
+						}
+					  },
+					  "next_id": null
+					}
+				`, ""),
+			}
+		})
+
+		It("returns the certificate and key with the binding", func() {
+			bindings, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(bindings).To(ConsistOf(
+				v1.Binding{AppId: "app-1", Hostname: "h", Drain: "syslog://some.url"},
+				v1.Binding{
+					AppId:      "app-1",
+					Hostname:   "h",
+					Drain:      "syslog-tls://secure.url",
+					ClientCert: "some-cert",
+					ClientKey:  "some-key",
+				},
+			))
+		})
+	})
+
+	It("returns the CA bundle and SPKI pins with the binding", func() {
+		getter.getResponses = []*http.Response{
+			pageResponse(`
+				{
+				  "results": {
+					"app-1": {
+					  "drains": [
+						{
+						  "url": "https://secure.url",
+						  "ca": "some-ca",
+						  "spki_pins": ["pin-1", "pin-2"]
+						}
+					  ],
+					  "hostname": "h"
+					}
+				  },
+				  "next_id": null
+				}
+			`, ""),
+		}
+
+		bindings, err := fetcher.FetchBindings()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(bindings).To(ConsistOf(v1.Binding{
+			AppId:    "app-1",
+			Hostname: "h",
+			Drain:    "https://secure.url",
+			CaCert:   "some-ca",
+			SpkiPins: "pin-1,pin-2",
+		}))
+	})
+
+	It("returns an error for drains that are neither a URL nor an object", func() {
+		getter.getResponses = []*http.Response{
+			pageResponse(`{"results": {"app-1": {"drains": [42], "hostname": "h"}}, "next_id": null}`, ""),
+		}
+
+		_, err := fetcher.FetchBindings()
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("when the getter supports conditional requests", func() {
+		var getter *SpyConditionalGetter
+
+		BeforeEach(func() {
+			getter = &SpyConditionalGetter{}
+			getter.getResponses = []*http.Response{
+				pageResponse(`{"results": {"app-1": {"drains": ["syslog://some.url"], "hostname": "h"}}, "next_id": 50}`, "etag-1"),
+				pageResponse(`{"results": {"app-2": {"drains": ["syslog://other.url"], "hostname": "h"}}, "next_id": null}`, "etag-2"),
+			}
+			fetcher = ingress.NewBindingFetcher(getter)
+
+			_, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fetcher.Changed()).To(BeTrue())
+		})
+
+		It("requests the pages with the ETags of the previous fetch", func() {
+			getter.conditionalResponses = []*http.Response{
+				{StatusCode: http.StatusNotModified},
+				{StatusCode: http.StatusNotModified},
+			}
+
+			_, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(getter.getCalled).To(Equal(2))
+			Expect(getter.conditionalNextIDs).To(Equal([]int{0, 50}))
+			Expect(getter.etags).To(Equal([]string{"etag-1", "etag-2"}))
+		})
+
+		It("reuses the pages that did not change", func() {
+			getter.conditionalResponses = []*http.Response{
+				{StatusCode: http.StatusNotModified},
+				{StatusCode: http.StatusNotModified},
+			}
+
+			bindings, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(bindings).To(ConsistOf(
+				v1.Binding{AppId: "app-1", Hostname: "h", Drain: "syslog://some.url"},
+				v1.Binding{AppId: "app-2", Hostname: "h", Drain: "syslog://other.url"},
+			))
+			Expect(fetcher.Changed()).To(BeFalse())
+		})
+
+		It("reports a change if a page changed", func() {
+			getter.conditionalResponses = []*http.Response{
+				{StatusCode: http.StatusNotModified},
+				pageResponse(`{"results": {"app-3": {"drains": ["syslog://new.url"], "hostname": "h"}}, "next_id": null}`, "etag-3"),
+			}
+
+			bindings, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(bindings).To(ConsistOf(
+				v1.Binding{AppId: "app-1", Hostname: "h", Drain: "syslog://some.url"},
+				v1.Binding{AppId: "app-3", Hostname: "h", Drain: "syslog://new.url"},
+			))
+			Expect(fetcher.Changed()).To(BeTrue())
+		})
+
+		It("does not report a change if a modified page has the same bindings", func() {
+			getter.conditionalResponses = []*http.Response{
+				{StatusCode: http.StatusNotModified},
+				pageResponse(`{"results": {"app-2": {"drains": ["syslog://other.url"], "hostname": "h"}}, "next_id": null}`, "etag-3"),
+			}
+
+			_, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fetcher.Changed()).To(BeFalse())
+		})
+
+		It("fetches all pages unconditionally after the resync interval", func() {
+			fetcher = ingress.NewBindingFetcher(getter, ingress.WithResyncInterval(time.Nanosecond))
+			getter.getResponses = append(getter.getResponses,
+				pageResponse(`{"results": {}, "next_id": null}`, "etag-1"),
+				pageResponse(`{"results": {}, "next_id": null}`, "etag-1"),
+			)
+
+			_, err := fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+			time.Sleep(time.Millisecond)
+			_, err = fetcher.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(getter.getCalled).To(Equal(4))
+			Expect(getter.conditionalNextIDs).To(BeEmpty())
+			Expect(fetcher.Changed()).To(BeTrue())
+		})
+	})
 })
 
+func pageResponse(body, etag string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": []string{etag}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+type SpyConditionalGetter struct {
+	SpyGetter
+	conditionalNextIDs   []int
+	etags                []string
+	conditionalResponses []*http.Response
+}
+
+func (s *SpyConditionalGetter) GetIfNoneMatch(nextID int, etag string) (*http.Response, error) {
+	s.conditionalNextIDs = append(s.conditionalNextIDs, nextID)
+	s.etags = append(s.etags, etag)
+	resp := s.conditionalResponses[0]
+	s.conditionalResponses = s.conditionalResponses[1:]
+
+	return resp, nil
+}
+
 type SpyGetter struct {
 	currentResponse int
 	getCalled       int
@@ -93,6 +93,36 @@ func (i *BlacklistRanges) ResolveAddr(host string) (net.IP, error) {
 	return ipAddress, nil
 }
 
+// ResolveAddrs returns every IP address of the host.
+func (i *BlacklistRanges) ResolveAddrs(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return nil, fmt.Errorf("unable to resolve DNS entry: %s", host)
+	}
+
+	return ips, nil
+}
+
+// LookupSRV returns the target hosts of the _syslog._tcp SRV records of the
+// host. Adapters connect to these targets for syslog drains without a port.
+func (i *BlacklistRanges) LookupSRV(host string) ([]string, error) {
+	_, records, err := net.LookupSRV("syslog", "tcp", host)
+	if err != nil || len(records) == 0 {
+		return nil, fmt.Errorf("unable to resolve SRV records: %s", host)
+	}
+
+	targets := make([]string, 0, len(records))
+	for _, r := range records {
+		targets = append(targets, strings.TrimSuffix(r.Target, "."))
+	}
+
+	return targets, nil
+}
+
 func (i *BlacklistRanges) ParseHost(drainURL string) (string, string, error) {
 	testURL, err := url.Parse(drainURL)
 	if err != nil {
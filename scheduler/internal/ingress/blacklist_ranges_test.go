@@ -119,6 +119,24 @@ var _ = Describe("BlacklistRanges", func() {
 		})
 	})
 
+	Describe("ResolveAddrs()", func() {
+		It("returns IP addresses as they are", func() {
+			ranges, _ := ingress.NewBlacklistRanges()
+
+			ips, err := ranges.ResolveAddrs("10.10.10.10")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ips).To(HaveLen(1))
+			Expect(ips[0].String()).To(Equal("10.10.10.10"))
+		})
+
+		It("returns an error when it fails to resolve", func() {
+			ranges, _ := ingress.NewBlacklistRanges()
+
+			_, err := ranges.ResolveAddrs("vcap.me.junky-garbage")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
 	Describe("UnmarshalEnv", func() {
 		It("returns an error for non-valid input", func() {
 			bl := &ingress.BlacklistRanges{}
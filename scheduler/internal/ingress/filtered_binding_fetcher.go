@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
 	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
@@ -15,9 +16,16 @@ type BindingReader interface {
 	FetchBindings() (appBindings []v1.Binding, err error)
 }
 
+// ChangeReporter is implemented by BindingReaders that can tell whether the
+// bindings changed since the previous fetch.
+type ChangeReporter interface {
+	Changed() bool
+}
+
 type IPChecker interface {
 	ParseHost(url string) (string, string, error)
-	ResolveAddr(host string) (net.IP, error)
+	ResolveAddrs(host string) ([]net.IP, error)
+	LookupSRV(host string) ([]string, error)
 	CheckBlacklist(ip net.IP) error
 }
 
@@ -30,6 +38,9 @@ type FilteredBindingFetcher struct {
 	ipChecker IPChecker
 	br        BindingReader
 	logClient LogClient
+
+	previous map[v1.Binding]int
+	changed  bool
 }
 
 func NewFilteredBindingFetcher(c IPChecker, b BindingReader, lc LogClient) *FilteredBindingFetcher {
@@ -40,46 +51,131 @@ func NewFilteredBindingFetcher(c IPChecker, b BindingReader, lc LogClient) *Filt
 	}
 }
 
+// FetchBindings fetches the bindings and filters out the bindings with
+// invalid drains. The drains are validated on every fetch, even if the
+// BindingReader reports that the bindings did not change, so that drains
+// whose hosts start resolving to blacklisted addresses are removed.
 func (f *FilteredBindingFetcher) FetchBindings() ([]v1.Binding, int, error) {
 	sourceBindings, err := f.br.FetchBindings()
 	if err != nil {
 		return nil, 0, err
 	}
+
 	newBindings := []v1.Binding{}
 
 	for _, binding := range sourceBindings {
-		scheme, host, err := f.ipChecker.ParseHost(binding.Drain)
-		if err != nil {
-			log.Println(err)
-			f.emitErrorLog(binding.AppId, "Invalid syslog drain URL: parse failure")
+		scheme, ok := f.validDrain(binding.AppId, binding.Drain)
+		if !ok {
 			continue
 		}
 
-		if invalidScheme(scheme) {
+		if !f.validFallbacks(binding.AppId, binding.Drain, scheme) {
 			continue
 		}
 
-		ip, err := f.ipChecker.ResolveAddr(host)
+		newBindings = append(newBindings, binding)
+	}
+
+	counts := make(map[v1.Binding]int, len(newBindings))
+	for _, b := range newBindings {
+		counts[b]++
+	}
+
+	cr, ok := f.br.(ChangeReporter)
+	sourceChanged := !ok || cr.Changed()
+	f.changed = sourceChanged || f.previous == nil || !sameBindings(counts, f.previous)
+	f.previous = counts
+
+	return newBindings, len(sourceBindings) - len(newBindings), nil
+}
+
+// Changed returns false if the last fetch returned the bindings of the fetch
+// before it.
+func (f *FilteredBindingFetcher) Changed() bool {
+	return f.changed
+}
+
+// validDrain parses the drain URL and ensures it has a supported scheme and
+// that every host the adapters may connect to resolves and is not
+// blacklisted. For syslog drains without a port these are the targets of
+// the SRV records of the host. The scheme is returned for any drain URL that
+// could be parsed.
+func (f *FilteredBindingFetcher) validDrain(appID, drain string) (string, bool) {
+	scheme, host, err := f.ipChecker.ParseHost(drain)
+	if err != nil {
+		log.Println(err)
+		f.emitErrorLog(appID, "Invalid syslog drain URL: parse failure")
+		return "", false
+	}
+
+	if invalidScheme(scheme) {
+		return scheme, false
+	}
+
+	hosts := []string{host}
+	if usesSRV(scheme, drain) {
+		hosts, err = f.ipChecker.LookupSRV(host)
 		if err != nil {
-			msg := fmt.Sprintf("Failed to resolve syslog drain host: %s", host)
+			msg := fmt.Sprintf("Failed to resolve syslog drain SRV records: %s", host)
 			log.Println(msg, err)
-			f.emitErrorLog(binding.AppId, msg)
-			continue
+			f.emitErrorLog(appID, msg)
+			return scheme, false
 		}
+	}
 
-		err = f.ipChecker.CheckBlacklist(ip)
-		if err != nil {
+	for _, h := range hosts {
+		if !f.validHost(appID, h) {
+			return scheme, false
+		}
+	}
+
+	return scheme, true
+}
+
+// validHost ensures that the host resolves and that none of its addresses
+// is blacklisted.
+func (f *FilteredBindingFetcher) validHost(appID, host string) bool {
+	ips, err := f.ipChecker.ResolveAddrs(host)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to resolve syslog drain host: %s", host)
+		log.Println(msg, err)
+		f.emitErrorLog(appID, msg)
+		return false
+	}
+
+	for _, ip := range ips {
+		if err := f.ipChecker.CheckBlacklist(ip); err != nil {
 			msg := fmt.Sprintf("Syslog drain blacklisted: %s (%s)", host, ip)
 			log.Println(msg, err)
-			f.emitErrorLog(binding.AppId, msg)
-			continue
+			f.emitErrorLog(appID, msg)
+			return false
 		}
+	}
 
-		newBindings = append(newBindings, binding)
+	return true
+}
+
+// validFallbacks ensures that the fallback drains given by the fallback query
+// parameters use the same scheme as the drain and are valid themselves.
+func (f *FilteredBindingFetcher) validFallbacks(appID, drain, scheme string) bool {
+	u, err := url.Parse(drain)
+	if err != nil {
+		return false
 	}
 
-	removed := len(sourceBindings) - len(newBindings)
-	return newBindings, removed, nil
+	for _, fallback := range u.Query()["fallback"] {
+		fu, err := url.Parse(fallback)
+		if err != nil || fu.Scheme != scheme {
+			f.emitErrorLog(appID, "Invalid syslog drain URL: fallback does not match drain scheme")
+			return false
+		}
+
+		if _, ok := f.validDrain(appID, fallback); !ok {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (f *FilteredBindingFetcher) emitErrorLog(appID, message string) {
@@ -91,6 +187,18 @@ func (f *FilteredBindingFetcher) emitErrorLog(appID, message string) {
 	f.logClient.EmitLog(message, option)
 }
 
+// usesSRV returns true for syslog and syslog-tls drains with a host name
+// and without a port, which adapters look up through _syslog._tcp SRV
+// records.
+func usesSRV(scheme, drain string) bool {
+	if scheme != "syslog" && scheme != "syslog-tls" {
+		return false
+	}
+
+	u, err := url.Parse(drain)
+	return err == nil && u.Port() == "" && net.ParseIP(u.Hostname()) == nil
+}
+
 func invalidScheme(scheme string) bool {
 	for _, s := range allowedSchemes {
 		if s == scheme {
@@ -2,8 +2,11 @@ package ingress_test
 
 import (
 	"errors"
+	"io/ioutil"
 	"net"
+	"net/http"
 	"net/url"
+	"strings"
 
 	loggregator "code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/ingress"
@@ -32,6 +35,42 @@ var _ = Describe("FilteredBindingFetcher", func() {
 		Expect(removed).To(Equal(0))
 	})
 
+	It("keeps the client certificates and trust of the drains", func() {
+		getter := &SpyGetter{
+			getResponses: []*http.Response{{
+				StatusCode: http.StatusOK,
+				Body: ioutil.NopCloser(strings.NewReader(`
+					{
+					  "results": {
+						"app-id": {
+						  "drains": [{"url": "syslog-tls://10.10.10.10:6514", "cert": "some-cert", "key": "some-key", "ca": "some-ca", "spki_pins": ["some-pin"]}],
+						  "hostname": "we.dont.care"
+						}
+					  }
+					}
+				`)),
+			}},
+		}
+
+		filter := ingress.NewFilteredBindingFetcher(
+			&spyIPChecker{},
+			ingress.NewBindingFetcher(getter),
+			&spyLogClient{},
+		)
+		actual, _, err := filter.FetchBindings()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(actual).To(ConsistOf(v1.Binding{
+			AppId:      "app-id",
+			Hostname:   "we.dont.care",
+			Drain:      "syslog-tls://10.10.10.10:6514",
+			ClientCert: "some-cert",
+			ClientKey:  "some-key",
+			CaCert:     "some-ca",
+			SpkiPins:   "some-pin",
+		}))
+	})
+
 	It("returns an error if the binding reader cannot fetch bindings", func() {
 		bindingReader := &SpyBindingReader{nil, errors.New("Woops")}
 
@@ -190,6 +229,247 @@ var _ = Describe("FilteredBindingFetcher", func() {
 			Expect(logClient.sourceType).To(Equal("LGR"))
 		})
 	})
+
+	Context("when the syslog drain has fallbacks", func() {
+		It("keeps bindings with valid fallbacks", func() {
+			input := []v1.Binding{
+				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.10?fallback=syslog://10.10.10.12"},
+			}
+
+			filter := ingress.NewFilteredBindingFetcher(
+				&spyIPChecker{},
+				&SpyBindingReader{bindings: input},
+				&spyLogClient{},
+			)
+
+			actual, removed, err := filter.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(input))
+			Expect(removed).To(Equal(0))
+		})
+
+		It("removes bindings whose fallback uses a different scheme", func() {
+			input := []v1.Binding{
+				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.10?fallback=https://10.10.10.12"},
+			}
+			logClient := &spyLogClient{}
+
+			filter := ingress.NewFilteredBindingFetcher(
+				&spyIPChecker{},
+				&SpyBindingReader{bindings: input},
+				logClient,
+			)
+
+			actual, removed, err := filter.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal([]v1.Binding{}))
+			Expect(removed).To(Equal(1))
+
+			Expect(logClient.calledWith).To(Equal("Invalid syslog drain URL: fallback does not match drain scheme"))
+			Expect(logClient.appID).To(Equal("app-id"))
+			Expect(logClient.sourceType).To(Equal("LGR"))
+		})
+
+		It("removes bindings whose fallback is blacklisted", func() {
+			input := []v1.Binding{
+				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.10?fallback=syslog://10.10.10.12"},
+			}
+
+			filter := ingress.NewFilteredBindingFetcher(
+				&spyIPChecker{
+					blacklistedIPs: []string{"10.10.10.12"},
+				},
+				&SpyBindingReader{bindings: input},
+				&spyLogClient{},
+			)
+
+			actual, removed, err := filter.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal([]v1.Binding{}))
+			Expect(removed).To(Equal(1))
+		})
+	})
+
+	Context("when the syslog drain has no port", func() {
+		var (
+			ipChecker *spyIPChecker
+			logClient *spyLogClient
+		)
+
+		BeforeEach(func() {
+			ipChecker = &spyIPChecker{
+				srvTargets: map[string][]string{
+					"drain.example.com": {"good.example.com", "internal.example.com"},
+				},
+				addrs: map[string][]net.IP{
+					"good.example.com":     {net.ParseIP("10.10.10.10")},
+					"internal.example.com": {net.ParseIP("10.10.10.11"), net.ParseIP("10.0.0.5")},
+				},
+			}
+			logClient = &spyLogClient{}
+		})
+
+		It("removes bindings with a blacklisted SRV target", func() {
+			ipChecker.blacklistedIPs = []string{"10.0.0.5"}
+			filter := ingress.NewFilteredBindingFetcher(
+				ipChecker,
+				&SpyBindingReader{bindings: []v1.Binding{
+					{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://drain.example.com"},
+				}},
+				logClient,
+			)
+
+			actual, removed, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(BeEmpty())
+			Expect(removed).To(Equal(1))
+			Expect(logClient.calledWith).To(Equal("Syslog drain blacklisted: internal.example.com (10.0.0.5)"))
+		})
+
+		It("does not look up SRV records of IP addresses", func() {
+			ipChecker.lookupSRVError = errors.New("no SRV records")
+			input := []v1.Binding{
+				{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.10/"},
+			}
+			filter := ingress.NewFilteredBindingFetcher(ipChecker, &SpyBindingReader{bindings: input}, logClient)
+
+			actual, _, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(input))
+			Expect(ipChecker.srvHosts).To(BeEmpty())
+		})
+
+		It("keeps bindings whose SRV targets are not blacklisted", func() {
+			input := []v1.Binding{
+				{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog-tls://drain.example.com"},
+			}
+			filter := ingress.NewFilteredBindingFetcher(ipChecker, &SpyBindingReader{bindings: input}, logClient)
+
+			actual, _, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(input))
+			Expect(ipChecker.srvHosts).To(Equal([]string{"drain.example.com"}))
+		})
+
+		It("removes bindings without SRV records", func() {
+			ipChecker.lookupSRVError = errors.New("no SRV records")
+			filter := ingress.NewFilteredBindingFetcher(
+				ipChecker,
+				&SpyBindingReader{bindings: []v1.Binding{
+					{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://drain.example.com"},
+				}},
+				logClient,
+			)
+
+			actual, _, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(BeEmpty())
+			Expect(logClient.calledWith).To(Equal("Failed to resolve syslog drain SRV records: drain.example.com"))
+		})
+
+		It("does not look up SRV records for HTTPS drains", func() {
+			filter := ingress.NewFilteredBindingFetcher(
+				ipChecker,
+				&SpyBindingReader{bindings: []v1.Binding{
+					{AppId: "app-id", Hostname: "we.dont.care", Drain: "https://10.10.10.10/logs"},
+				}},
+				logClient,
+			)
+
+			_, _, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ipChecker.srvHosts).To(BeEmpty())
+		})
+	})
+
+	It("removes bindings with any blacklisted address", func() {
+		ipChecker := &spyIPChecker{
+			addrs: map[string][]net.IP{
+				"drain.example.com": {net.ParseIP("10.10.10.10"), net.ParseIP("10.0.0.5")},
+			},
+			blacklistedIPs: []string{"10.0.0.5"},
+		}
+		filter := ingress.NewFilteredBindingFetcher(
+			ipChecker,
+			&SpyBindingReader{bindings: []v1.Binding{
+				{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://drain.example.com:514"},
+			}},
+			&spyLogClient{},
+		)
+
+		actual, _, err := filter.FetchBindings()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(actual).To(BeEmpty())
+		Expect(ipChecker.srvHosts).To(BeEmpty())
+	})
+
+	Context("when the binding reader reports changes", func() {
+		var (
+			bindingReader *spyChangeReportingBindingReader
+			ipChecker     *spyIPChecker
+			filter        *ingress.FilteredBindingFetcher
+		)
+
+		BeforeEach(func() {
+			bindingReader = &spyChangeReportingBindingReader{
+				SpyBindingReader: SpyBindingReader{bindings: []v1.Binding{
+					{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.10"},
+					{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.12"},
+				}},
+				changed: true,
+			}
+			ipChecker = &spyIPChecker{blacklistedIPs: []string{"10.10.10.12"}}
+			filter = ingress.NewFilteredBindingFetcher(ipChecker, bindingReader, &spyLogClient{})
+
+			_, _, err := filter.FetchBindings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ipChecker.resolveCalled).To(Equal(2))
+		})
+
+		It("validates the bindings again while unchanged", func() {
+			bindingReader.changed = false
+
+			actual, removed, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(ConsistOf(
+				v1.Binding{AppId: "app-id", Hostname: "we.dont.care", Drain: "syslog://10.10.10.10"},
+			))
+			Expect(removed).To(Equal(1))
+			Expect(ipChecker.resolveCalled).To(Equal(4))
+			Expect(filter.Changed()).To(BeFalse())
+		})
+
+		It("reports a change if a drain became invalid while unchanged", func() {
+			bindingReader.changed = false
+			ipChecker.blacklistedIPs = []string{"10.10.10.10", "10.10.10.12"}
+
+			actual, removed, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(BeEmpty())
+			Expect(removed).To(Equal(2))
+			Expect(filter.Changed()).To(BeTrue())
+		})
+
+		It("validates the bindings again when they changed", func() {
+			ipChecker.blacklistedIPs = nil
+
+			actual, removed, err := filter.FetchBindings()
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(HaveLen(2))
+			Expect(removed).To(Equal(0))
+			Expect(ipChecker.resolveCalled).To(Equal(4))
+			Expect(filter.Changed()).To(BeTrue())
+		})
+	})
 })
 
 type spyIPChecker struct {
@@ -199,9 +479,21 @@ type spyIPChecker struct {
 	parseHostError      error
 	parsedScheme        string
 	parsedHost          string
+	blacklistedIPs      []string
+	resolveCalled       int
+	addrs               map[string][]net.IP
+	srvTargets          map[string][]string
+	srvHosts            []string
+	lookupSRVError      error
 }
 
-func (s *spyIPChecker) CheckBlacklist(net.IP) error {
+func (s *spyIPChecker) CheckBlacklist(ip net.IP) error {
+	for _, b := range s.blacklistedIPs {
+		if ip.Equal(net.ParseIP(b)) {
+			return errors.New("blacklisted")
+		}
+	}
+
 	return s.checkBlacklistError
 }
 
@@ -211,11 +503,33 @@ func (s *spyIPChecker) ParseHost(URL string) (string, string, error) {
 		panic(err)
 	}
 
+	if s.parsedHost == "" {
+		return u.Scheme, u.Hostname(), s.parseHostError
+	}
+
 	return u.Scheme, s.parsedHost, s.parseHostError
 }
 
-func (s *spyIPChecker) ResolveAddr(host string) (net.IP, error) {
-	return s.resolvedIP, s.resolveAddrError
+func (s *spyIPChecker) ResolveAddrs(host string) ([]net.IP, error) {
+	s.resolveCalled++
+	if ips, ok := s.addrs[host]; ok {
+		return ips, s.resolveAddrError
+	}
+
+	if s.resolvedIP == nil {
+		return []net.IP{net.ParseIP(host)}, s.resolveAddrError
+	}
+
+	return []net.IP{s.resolvedIP}, s.resolveAddrError
+}
+
+func (s *spyIPChecker) LookupSRV(host string) ([]string, error) {
+	s.srvHosts = append(s.srvHosts, host)
+	if targets, ok := s.srvTargets[host]; ok {
+		return targets, s.lookupSRVError
+	}
+
+	return []string{host}, s.lookupSRVError
 }
 
 type spyLogClient struct {
@@ -244,3 +558,12 @@ type SpyBindingReader struct {
 func (s *SpyBindingReader) FetchBindings() ([]v1.Binding, error) {
 	return s.bindings, s.err
 }
+
+type spyChangeReportingBindingReader struct {
+	SpyBindingReader
+	changed bool
+}
+
+func (s *spyChangeReportingBindingReader) Changed() bool {
+	return s.changed
+}
@@ -0,0 +1,172 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+)
+
+// releaseTimeout is the timeout of the requests that release the lease.
+const releaseTimeout = 5 * time.Second
+
+// AdapterLock is a Lock that stores the lease on the adapters, so that
+// schedulers on different VMs can elect a leader without shared storage.
+// The lease is acquired if a majority of the adapters grant it. Each adapter
+// measures the expiry with its own clock, so the clocks of the schedulers
+// do not need to be in sync.
+//
+// The majority is counted among the adapters each scheduler knows of, so
+// the schedulers must discover the same adapters. While the adapters are
+// scaled two schedulers may briefly hold a majority of different sets.
+type AdapterLock struct {
+	addrs func() ([]string, error)
+	opts  []grpc.DialOption
+
+	mu      sync.Mutex
+	conns   map[string]*grpc.ClientConn
+	clients map[string]v1.AdapterClient
+}
+
+// NewAdapterLock returns an AdapterLock for the adapters addrs returns.
+// addrs is called on every Acquire and Release so that the lease follows
+// the adapters as they are added and removed. The adapters are dialed with
+// the given options.
+func NewAdapterLock(addrs func() ([]string, error), opts ...grpc.DialOption) *AdapterLock {
+	return &AdapterLock{
+		addrs:   addrs,
+		opts:    opts,
+		conns:   make(map[string]*grpc.ClientConn),
+		clients: make(map[string]v1.AdapterClient),
+	}
+}
+
+// Acquire implements Lock. The requests to the adapters time out after a
+// third of the ttl. If the lease is not acquired it is released on the
+// adapters that granted it, so that schedulers that campaign at the same
+// time do not block each other until the lease expires.
+func (l *AdapterLock) Acquire(holder string, ttl time.Duration) (bool, error) {
+	clients, err := l.adapters()
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		granted []v1.AdapterClient
+		lastErr error
+	)
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c v1.AdapterClient) {
+			defer wg.Done()
+
+			resp, err := c.AcquireLease(ctx, &v1.AcquireLeaseRequest{
+				Holder:    holder,
+				TtlMillis: int64(ttl / time.Millisecond),
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				lastErr = err
+				return
+			}
+			if resp.Acquired {
+				granted = append(granted, c)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if len(granted) > len(clients)/2 {
+		return true, nil
+	}
+
+	// The requests may have used up the timeout of the campaign, so the
+	// lease is released with a timeout of its own.
+	releaseCtx, releaseCancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer releaseCancel()
+
+	for _, c := range granted {
+		if _, err := c.ReleaseLease(releaseCtx, &v1.ReleaseLeaseRequest{Holder: holder}); err != nil {
+			log.Printf("failed to release lease on adapter: %s", err)
+		}
+	}
+
+	return false, lastErr
+}
+
+// Release implements Lock.
+func (l *AdapterLock) Release(holder string) error {
+	clients, err := l.adapters()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	var lastErr error
+	for _, c := range clients {
+		if _, err := c.ReleaseLease(ctx, &v1.ReleaseLeaseRequest{Holder: holder}); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// adapters returns the clients of the current adapters. Adapters that are
+// no longer given are closed.
+func (l *AdapterLock) adapters() ([]v1.AdapterClient, error) {
+	addrs, err := l.addrs()
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("no adapters to hold the lease")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current := make(map[string]bool, len(addrs))
+	clients := make([]v1.AdapterClient, 0, len(addrs))
+	for _, addr := range addrs {
+		current[addr] = true
+
+		c, ok := l.clients[addr]
+		if !ok {
+			conn, err := grpc.Dial(addr, l.opts...)
+			if err != nil {
+				return nil, err
+			}
+			c = v1.NewAdapterClient(conn)
+			l.conns[addr] = conn
+			l.clients[addr] = c
+		}
+		clients = append(clients, c)
+	}
+
+	for addr, conn := range l.conns {
+		if current[addr] {
+			continue
+		}
+
+		conn.Close()
+		delete(l.conns, addr)
+		delete(l.clients, addr)
+	}
+
+	return clients, nil
+}
@@ -0,0 +1,203 @@
+package leader_test
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	v1 "code.cloudfoundry.org/scalable-syslog/internal/api/v1"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/leader"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AdapterLock", func() {
+	var (
+		servers []*spyLeaseServer
+		addrs   []string
+		stops   []func()
+		lock    *leader.AdapterLock
+	)
+
+	BeforeEach(func() {
+		servers, addrs, stops = nil, nil, nil
+		for i := 0; i < 3; i++ {
+			s := &spyLeaseServer{}
+			addr, stop := startLeaseServer(s)
+			servers = append(servers, s)
+			addrs = append(addrs, addr)
+			stops = append(stops, stop)
+		}
+
+		lock = leader.NewAdapterLock(
+			func() ([]string, error) { return addrs, nil },
+			grpc.WithInsecure(),
+		)
+	})
+
+	AfterEach(func() {
+		for _, stop := range stops {
+			stop()
+		}
+	})
+
+	It("grants the lease to one holder at a time", func() {
+		ok, err := lock.Acquire("a", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		for _, s := range servers {
+			Expect(s.holder()).To(Equal("a"))
+		}
+
+		ok, err = lock.Acquire("b", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("shares the lease between AdapterLocks of the same adapters", func() {
+		other := leader.NewAdapterLock(
+			func() ([]string, error) { return addrs, nil },
+			grpc.WithInsecure(),
+		)
+
+		ok, err := lock.Acquire("a", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		ok, err = other.Acquire("b", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		Expect(lock.Release("a")).To(Succeed())
+		ok, err = other.Acquire("b", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("acquires the lease from a majority of the adapters", func() {
+		servers[0].take("b", time.Minute)
+
+		ok, err := lock.Acquire("a", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("acquires the lease while a minority of the adapters is down", func() {
+		stops[0]()
+
+		ok, err := lock.Acquire("a", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("releases the lease on the adapters that granted it without a majority", func() {
+		servers[0].take("b", time.Minute)
+		servers[1].take("c", time.Minute)
+
+		ok, err := lock.Acquire("a", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		Expect(servers[2].holder()).To(BeEmpty())
+	})
+
+	It("releases the lease after the requests to the adapters timed out", func() {
+		servers[0].take("b", time.Minute)
+		servers[1].delay = 200 * time.Millisecond
+
+		ok, err := lock.Acquire("a", 300*time.Millisecond)
+		Expect(err).To(HaveOccurred())
+		Expect(ok).To(BeFalse())
+
+		Expect(servers[2].holder()).To(BeEmpty())
+	})
+
+	It("returns an error if a majority of the adapters is down", func() {
+		stops[0]()
+		stops[1]()
+
+		ok, err := lock.Acquire("a", time.Minute)
+		Expect(err).To(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns an error without adapters", func() {
+		addrs = nil
+
+		_, err := lock.Acquire("a", time.Minute)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+func startLeaseServer(s *spyLeaseServer) (string, func()) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	Expect(err).ToNot(HaveOccurred())
+
+	grpcServer := grpc.NewServer()
+	v1.RegisterAdapterServer(grpcServer, s)
+	go grpcServer.Serve(lis)
+
+	var once sync.Once
+	return lis.Addr().String(), func() {
+		once.Do(grpcServer.Stop)
+	}
+}
+
+// spyLeaseServer implements the lease of an adapter. The other methods of
+// the adapter are not used by the AdapterLock.
+type spyLeaseServer struct {
+	v1.AdapterServer
+
+	// delay is how long AcquireLease takes to respond.
+	delay time.Duration
+
+	mu      sync.Mutex
+	leaseOf string
+	expires time.Time
+}
+
+func (s *spyLeaseServer) AcquireLease(ctx context.Context, req *v1.AcquireLeaseRequest) (*v1.AcquireLeaseResponse, error) {
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leaseOf != "" && s.leaseOf != req.Holder && time.Now().Before(s.expires) {
+		return &v1.AcquireLeaseResponse{}, nil
+	}
+	s.leaseOf = req.Holder
+	s.expires = time.Now().Add(time.Duration(req.TtlMillis) * time.Millisecond)
+
+	return &v1.AcquireLeaseResponse{Acquired: true}, nil
+}
+
+func (s *spyLeaseServer) ReleaseLease(ctx context.Context, req *v1.ReleaseLeaseRequest) (*v1.ReleaseLeaseResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leaseOf == req.Holder {
+		s.leaseOf = ""
+	}
+
+	return &v1.ReleaseLeaseResponse{}, nil
+}
+
+func (s *spyLeaseServer) take(holder string, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leaseOf = holder
+	s.expires = time.Now().Add(ttl)
+}
+
+func (s *spyLeaseServer) holder() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.leaseOf
+}
@@ -0,0 +1,121 @@
+package leader
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-loggregator/pulseemitter"
+)
+
+// HealthEmitter reports the leadership on the health endpoint.
+type HealthEmitter interface {
+	SetValue(name string, v interface{})
+}
+
+// MetricEmitter creates the leadership metric.
+type MetricEmitter interface {
+	NewGaugeMetric(name, unit string, opts ...pulseemitter.MetricOption) pulseemitter.GaugeMetric
+}
+
+// Elector campaigns for the lease of a Lock. The scheduler that holds the
+// lease orchestrates the adapters while the others wait as hot standbys.
+type Elector struct {
+	lock   Lock
+	id     string
+	ttl    time.Duration
+	health HealthEmitter
+	gauge  pulseemitter.GaugeMetric
+
+	mu      sync.Mutex
+	leader  bool
+	renewed time.Time
+}
+
+// NewElector returns an Elector that campaigns as the given id for a lease
+// of the given ttl.
+func NewElector(lock Lock, id string, ttl time.Duration, h HealthEmitter, m MetricEmitter) *Elector {
+	// metric-documentation-v2: (scheduler.leader) 1 if the scheduler holds
+	// the lease and orchestrates the adapters, 0 if it is a standby.
+	gauge := m.NewGaugeMetric("leader", "bool",
+		pulseemitter.WithVersion(2, 0),
+	)
+
+	e := &Elector{
+		lock:   lock,
+		id:     id,
+		ttl:    ttl,
+		health: h,
+		gauge:  gauge,
+	}
+	e.report(false)
+
+	return e
+}
+
+// IsLeader returns true if the lease was acquired or renewed within the ttl.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.leader && time.Since(e.renewed) < e.ttl
+}
+
+// Campaign acquires or renews the lease once. The elector steps down if the
+// lock fails, since it can no longer tell whether it holds the lease.
+func (e *Elector) Campaign() {
+	start := time.Now()
+	ok, err := e.lock.Acquire(e.id, e.ttl)
+	if err != nil {
+		log.Printf("failed to acquire leader lease: %s", err)
+		ok = false
+	}
+
+	e.mu.Lock()
+	changed := ok != e.leader
+	e.leader = ok
+	if ok {
+		e.renewed = start
+	}
+	e.mu.Unlock()
+
+	if changed {
+		if ok {
+			log.Printf("%s became the leader", e.id)
+		} else {
+			log.Printf("%s is no longer the leader", e.id)
+		}
+	}
+	e.report(ok)
+}
+
+// Resign releases the lease so that a standby can take over without waiting
+// for the lease to expire.
+func (e *Elector) Resign() {
+	e.mu.Lock()
+	e.leader = false
+	e.mu.Unlock()
+	e.report(false)
+
+	if err := e.lock.Release(e.id); err != nil {
+		log.Printf("failed to release leader lease: %s", err)
+	}
+}
+
+// Run campaigns three times per ttl so that the lease is renewed before it
+// expires.
+func (e *Elector) Run() {
+	e.Campaign()
+	for range time.Tick(e.ttl / 3) {
+		e.Campaign()
+	}
+}
+
+func (e *Elector) report(leader bool) {
+	var v float64
+	if leader {
+		v = 1
+	}
+	e.gauge.Set(v)
+	e.health.SetValue("leader", leader)
+}
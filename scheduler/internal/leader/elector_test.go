@@ -0,0 +1,101 @@
+package leader_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/scalable-syslog/internal/testhelper"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/leader"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Elector", func() {
+	var (
+		lock    *leader.MemoryLock
+		health  *spyHealth
+		metrics *testhelper.SpyMetricClient
+	)
+
+	BeforeEach(func() {
+		lock = leader.NewMemoryLock()
+		health = &spyHealth{}
+		metrics = testhelper.NewMetricClient()
+	})
+
+	It("elects one leader", func() {
+		a := leader.NewElector(lock, "a", time.Minute, health, metrics)
+		b := leader.NewElector(lock, "b", time.Minute, &spyHealth{}, testhelper.NewMetricClient())
+
+		Expect(a.IsLeader()).To(BeFalse())
+		Expect(health.values["leader"]).To(BeFalse())
+
+		a.Campaign()
+		b.Campaign()
+
+		Expect(a.IsLeader()).To(BeTrue())
+		Expect(b.IsLeader()).To(BeFalse())
+		Expect(health.values["leader"]).To(BeTrue())
+		Expect(metrics.GetMetric("leader").GaugeValue()).To(Equal(1.0))
+	})
+
+	It("hands over leadership when the leader resigns", func() {
+		a := leader.NewElector(lock, "a", time.Minute, health, metrics)
+		b := leader.NewElector(lock, "b", time.Minute, &spyHealth{}, testhelper.NewMetricClient())
+		a.Campaign()
+
+		a.Resign()
+		b.Campaign()
+
+		Expect(a.IsLeader()).To(BeFalse())
+		Expect(b.IsLeader()).To(BeTrue())
+		Expect(metrics.GetMetric("leader").GaugeValue()).To(Equal(0.0))
+	})
+
+	It("is not the leader once the lease was not renewed within the ttl", func() {
+		a := leader.NewElector(lock, "a", 10*time.Millisecond, health, metrics)
+		a.Campaign()
+		Expect(a.IsLeader()).To(BeTrue())
+
+		Eventually(a.IsLeader).Should(BeFalse())
+	})
+
+	It("steps down if the lock fails", func() {
+		failing := &spyLock{}
+		a := leader.NewElector(failing, "a", time.Minute, health, metrics)
+		failing.ok = true
+		a.Campaign()
+		Expect(a.IsLeader()).To(BeTrue())
+
+		failing.err = errors.New("some-error")
+		a.Campaign()
+
+		Expect(a.IsLeader()).To(BeFalse())
+		Expect(health.values["leader"]).To(BeFalse())
+	})
+})
+
+type spyHealth struct {
+	values map[string]interface{}
+}
+
+func (s *spyHealth) SetValue(name string, v interface{}) {
+	if s.values == nil {
+		s.values = make(map[string]interface{})
+	}
+	s.values[name] = v
+}
+
+type spyLock struct {
+	ok  bool
+	err error
+}
+
+func (s *spyLock) Acquire(holder string, ttl time.Duration) (bool, error) {
+	return s.ok, s.err
+}
+
+func (s *spyLock) Release(holder string) error {
+	return s.err
+}
@@ -0,0 +1,149 @@
+// Package leader elects the scheduler that orchestrates the adapters.
+package leader
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Lock is a lease that at most one holder owns at a time.
+type Lock interface {
+	// Acquire acquires the lease for the holder or renews it if the holder
+	// already owns it. The lease expires after the ttl. It returns false if
+	// another holder owns a lease that has not expired.
+	Acquire(holder string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lease if the holder owns it.
+	Release(holder string) error
+}
+
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+func (l lease) acquire(holder string, ttl time.Duration, now time.Time) (lease, bool) {
+	if l.Holder != "" && l.Holder != holder && now.Before(l.Expires) {
+		return l, false
+	}
+
+	return lease{Holder: holder, Expires: now.Add(ttl)}, true
+}
+
+// MemoryLock is a Lock for schedulers that run in the same process.
+type MemoryLock struct {
+	mu    sync.Mutex
+	lease lease
+}
+
+// NewMemoryLock returns a MemoryLock that nobody holds.
+func NewMemoryLock() *MemoryLock {
+	return &MemoryLock{}
+}
+
+// Acquire implements Lock.
+func (l *MemoryLock) Acquire(holder string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var ok bool
+	l.lease, ok = l.lease.acquire(holder, ttl, time.Now())
+
+	return ok, nil
+}
+
+// Release implements Lock.
+func (l *MemoryLock) Release(holder string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lease.Holder == holder {
+		l.lease = lease{}
+	}
+
+	return nil
+}
+
+// FileLock is a Lock that stores the lease in a file. The file is locked
+// with flock while the lease is read and written, so the file must be on a
+// filesystem that supports flock between all schedulers. Expiry times are
+// compared with the clock of each scheduler, so the clocks must be in sync
+// to well within the ttl. Schedulers on different VMs usually do not share
+// such a filesystem and should use an AdapterLock.
+type FileLock struct {
+	path string
+}
+
+// NewFileLock returns a FileLock that stores the lease in the file at the
+// given path. The file is created if it does not exist.
+func NewFileLock(path string) *FileLock {
+	return &FileLock{
+		path: path,
+	}
+}
+
+// Acquire implements Lock.
+func (l *FileLock) Acquire(holder string, ttl time.Duration) (bool, error) {
+	var ok bool
+	err := l.update(func(current lease) (lease, bool) {
+		var next lease
+		next, ok = current.acquire(holder, ttl, time.Now())
+		return next, ok
+	})
+
+	return ok, err
+}
+
+// Release implements Lock.
+func (l *FileLock) Release(holder string) error {
+	return l.update(func(current lease) (lease, bool) {
+		return lease{}, current.Holder == holder
+	})
+}
+
+// update replaces the lease in the file with the lease f returns while
+// holding an exclusive flock on the file. The file is left as it is if f
+// returns false.
+func (l *FileLock) update(f func(lease) (lease, bool)) error {
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	var current lease
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > 0 {
+		if err := json.NewDecoder(file).Decode(&current); err != nil {
+			return err
+		}
+	}
+
+	next, ok := f(current)
+	if !ok {
+		return nil
+	}
+
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(next); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
@@ -0,0 +1,114 @@
+package leader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/leader"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "leader")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	locks := map[string]func() leader.Lock{
+		"MemoryLock": func() leader.Lock {
+			return leader.NewMemoryLock()
+		},
+		"FileLock": func() leader.Lock {
+			return leader.NewFileLock(filepath.Join(dir, "lease"))
+		},
+	}
+
+	for name, newLock := range locks {
+		newLock := newLock
+
+		Describe(name, func() {
+			var lock leader.Lock
+
+			BeforeEach(func() {
+				lock = newLock()
+			})
+
+			It("grants the lease to one holder at a time", func() {
+				ok, err := lock.Acquire("a", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				ok, err = lock.Acquire("b", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+
+			It("renews the lease of the holder", func() {
+				ok, err := lock.Acquire("a", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				ok, err = lock.Acquire("a", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+			})
+
+			It("grants an expired lease to another holder", func() {
+				ok, err := lock.Acquire("a", time.Millisecond)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				time.Sleep(5 * time.Millisecond)
+
+				ok, err = lock.Acquire("b", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+			})
+
+			It("grants a released lease to another holder", func() {
+				_, err := lock.Acquire("a", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(lock.Release("b")).To(Succeed())
+				ok, err := lock.Acquire("b", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+
+				Expect(lock.Release("a")).To(Succeed())
+				ok, err = lock.Acquire("b", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+			})
+		})
+	}
+
+	It("shares the lease between FileLocks of the same file", func() {
+		path := filepath.Join(dir, "lease")
+
+		ok, err := leader.NewFileLock(path).Acquire("a", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		ok, err = leader.NewFileLock(path).Acquire("b", time.Minute)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns an error if the FileLock can not open the file", func() {
+		lock := leader.NewFileLock(filepath.Join(dir, "missing", "lease"))
+
+		_, err := lock.Acquire("a", time.Minute)
+		Expect(err).To(HaveOccurred())
+	})
+})
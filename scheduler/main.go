@@ -13,6 +13,7 @@ import (
 	"code.cloudfoundry.org/go-loggregator/pulseemitter"
 	"code.cloudfoundry.org/scalable-syslog/internal/api"
 	"code.cloudfoundry.org/scalable-syslog/scheduler/app"
+	"code.cloudfoundry.org/scalable-syslog/scheduler/internal/leader"
 )
 
 func main() {
@@ -70,17 +71,43 @@ func main() {
 		pulseemitter.WithSourceID("drain_scheduler"),
 	)
 
+	opts := []app.SchedulerOption{
+		app.WithHealthAddr(cfg.HealthHostport),
+		app.WithHTTPClient(api.NewHTTPSClient(apiTLSConfig, 5*time.Second)),
+		app.WithBlacklist(cfg.Blacklist),
+		app.WithPollingInterval(cfg.APIPollingInterval),
+		app.WithAPIBatchSize(cfg.APIBatchSize),
+		app.WithAPIResyncInterval(cfg.APIResyncInterval),
+		app.WithAdapterReplicas(cfg.AdapterReplicas),
+		app.WithAdapterZones(cfg.AdapterZones),
+		app.WithAdapterSource(cfg.AdapterSource),
+		app.WithAdapterUnhealthyThreshold(cfg.AdapterUnhealthyThreshold),
+		app.WithRebalancing(
+			cfg.RebalanceMaxMoves,
+			float64(cfg.RebalanceTolerancePercent)/100,
+		),
+	}
+	switch {
+	case cfg.LeaderLockAdapters:
+		opts = append(opts, app.WithAdapterLeaderElection(
+			cfg.LeaderID,
+			cfg.LeaderLeaseTTL,
+		))
+	case cfg.LeaderLockFile != "":
+		opts = append(opts, app.WithLeaderElection(
+			leader.NewFileLock(cfg.LeaderLockFile),
+			cfg.LeaderID,
+			cfg.LeaderLeaseTTL,
+		))
+	}
+
 	scheduler := app.NewScheduler(
 		cfg.APIURL,
 		cfg.AdapterAddrs,
 		adapterTLSConfig,
 		metricClient,
 		logClient,
-		app.WithHealthAddr(cfg.HealthHostport),
-		app.WithHTTPClient(api.NewHTTPSClient(apiTLSConfig, 5*time.Second)),
-		app.WithBlacklist(cfg.Blacklist),
-		app.WithPollingInterval(cfg.APIPollingInterval),
-		app.WithAPIBatchSize(cfg.APIBatchSize),
+		opts...,
 	)
 	scheduler.Start()
 